@@ -1,8 +1,36 @@
 package x
 
+import (
+	"context"
+	"errors"
+)
+
+// ErrTimeout is returned by websocket requests which got no response in time.
+var ErrTimeout = errors.New("Timeout waiting for response")
+
 type HttpApiClient interface {
+	GetTicker(pair Pair) (Ticker, error)
+	GetAllTickers() (map[Pair]Ticker, error)
 	GetKlines(pair Pair, period string, since uint64, size uint16) ([]Kline, error)
 }
 
+type TradeApiClient interface {
+	GetAccount(accessKey, secretKey string) (Account, error)
+	PlaceOrder(symbol string, price, amount float64, tradeType TradeType, accessKey, secretKey string) (uint64, error)
+	PlaceOrders(symbol string, requests []OrderRequest, accessKey, secretKey string) []OrderResult
+	CancelOrder(symbol string, id uint64, accessKey, secretKey string) error
+	CancelOrders(symbol string, ids []uint64, accessKey, secretKey string) []OrderResult
+	GetOrder(symbol string, id uint64, accessKey, secretKey string) (Order, error)
+	GetOpenOrders(symbol string, accessKey, secretKey string) ([]Order, error)
+}
+
 type WsApiClient interface {
+	Connect(ctx context.Context) error
+	Disconnect()
+	SubscribeTicker(symbol string, callback func(ticker Ticker)) (Subscription, error)
+}
+
+// Subscription is a handle to a websocket subscription, Unsubscribe may be called more than once.
+type Subscription interface {
+	Unsubscribe() error
 }
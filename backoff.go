@@ -0,0 +1,31 @@
+package x
+
+import "time"
+
+// Backoff computes exponentially growing delays between retries, starting at Min and capped at Max.
+type Backoff struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// DefaultBackoff is used in place of a nil Backoff.
+var DefaultBackoff = NewBackoff(time.Second, time.Minute)
+
+func NewBackoff(min, max time.Duration) *Backoff {
+	return &Backoff{Min: min, Max: max}
+}
+
+// Duration returns the delay before the retry following attempt, attempts start at 0.
+func (b *Backoff) Duration(attempt int) time.Duration {
+	if b == nil {
+		b = DefaultBackoff
+	}
+	d := b.Min
+	for i := 0; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	if d > b.Max {
+		return b.Max
+	}
+	return d
+}
@@ -0,0 +1,22 @@
+package x
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBackoff_Duration(t *testing.T) {
+	b := NewBackoff(100*time.Millisecond, time.Second)
+	assert.Equal(t, 100*time.Millisecond, b.Duration(0))
+	assert.Equal(t, 200*time.Millisecond, b.Duration(1))
+	assert.Equal(t, 800*time.Millisecond, b.Duration(3))
+	assert.Equal(t, time.Second, b.Duration(4))
+	assert.Equal(t, time.Second, b.Duration(1000))
+}
+
+func TestBackoff_DurationNil(t *testing.T) {
+	var b *Backoff
+	assert.Equal(t, time.Second, b.Duration(0))
+	assert.Equal(t, time.Minute, b.Duration(1000))
+}
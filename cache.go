@@ -0,0 +1,121 @@
+package x
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Group coalesces concurrent calls with the same key into a single execution whose result is shared by every caller.
+type Group struct {
+	mutex sync.Mutex
+	calls map[string]*flight
+}
+
+type flight struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// Do runs fn once for every key in flight. A panic in fn is returned as an error to every caller.
+func (g *Group) Do(key string, fn func() (interface{}, error)) (value interface{}, err error) {
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*flight{}
+	}
+	if f, ok := g.calls[key]; ok {
+		g.mutex.Unlock()
+		<-f.done
+		return f.value, f.err
+	}
+	f := &flight{done: make(chan struct{})}
+	g.calls[key] = f
+	g.mutex.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			f.value, f.err = nil, fmt.Errorf("Panic while loading %s: %v", key, r)
+		}
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+		close(f.done)
+		value, err = f.value, f.err
+	}()
+	f.value, f.err = fn()
+	return f.value, f.err
+}
+
+// Cache keeps loaded values for TTL. Values older than Refresh are still served but reloaded in the background,
+// a zero Refresh disables background reloading.
+type Cache struct {
+	TTL     time.Duration
+	Refresh time.Duration
+	Clock   Clock
+	mutex   sync.Mutex
+	entries map[string]*entry
+	group   Group
+}
+
+type entry struct {
+	value      interface{}
+	time       time.Time
+	refreshing bool
+}
+
+func NewCache(ttl, refresh time.Duration) *Cache {
+	return &Cache{TTL: ttl, Refresh: refresh, entries: map[string]*entry{}}
+}
+
+func (c *Cache) Get(key string, load func() (interface{}, error)) (interface{}, error) {
+	c.mutex.Lock()
+	if e, ok := c.entries[key]; ok {
+		age := c.Clock.Now().Sub(e.time)
+		if age < c.TTL {
+			if c.Refresh > 0 && age >= c.Refresh && !e.refreshing {
+				e.refreshing = true
+				go c.reload(key, e, load)
+			}
+			c.mutex.Unlock()
+			return e.value, nil
+		}
+	}
+	c.mutex.Unlock()
+
+	return c.group.Do(key, func() (interface{}, error) {
+		return c.load(key, load)
+	})
+}
+
+func (c *Cache) Invalidate(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.entries, key)
+}
+
+func (c *Cache) reload(key string, stale *entry, load func() (interface{}, error)) {
+	_, err := c.group.Do(key, func() (interface{}, error) {
+		return c.load(key, load)
+	})
+	if err != nil {
+		c.mutex.Lock()
+		stale.refreshing = false
+		c.mutex.Unlock()
+	}
+}
+
+func (c *Cache) load(key string, load func() (interface{}, error)) (interface{}, error) {
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]*entry{}
+	}
+	c.entries[key] = &entry{value: value, time: c.Clock.Now()}
+	return value, nil
+}
@@ -0,0 +1,155 @@
+package x
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroup_Do(t *testing.T) {
+	var g Group
+	var calls int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := g.Do("ticker", func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return 42, nil
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, 42, v)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), calls)
+
+	g.Do("ticker", func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, nil
+	})
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestGroup_DoPanic(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	waiter := make(chan error)
+	go func() {
+		_, err := g.Do("ticker", func() (interface{}, error) {
+			<-release
+			panic("boom")
+		})
+		waiter <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	go func() {
+		_, err := g.Do("ticker", func() (interface{}, error) {
+			return 42, nil
+		})
+		waiter <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		err := <-waiter
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "boom")
+	}
+}
+
+type fakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestCache_Get(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1516536000, 0)}
+	c := NewCache(time.Minute, 0)
+	c.Clock = clock.Now
+
+	var loads int
+	load := func() (interface{}, error) {
+		loads++
+		return loads, nil
+	}
+
+	v, _ := c.Get("symbols", load)
+	assert.Equal(t, 1, v)
+	clock.Advance(59 * time.Second)
+	v, _ = c.Get("symbols", load)
+	assert.Equal(t, 1, v)
+	clock.Advance(time.Second)
+	v, _ = c.Get("symbols", load)
+	assert.Equal(t, 2, v)
+
+	c.Invalidate("symbols")
+	v, _ = c.Get("symbols", load)
+	assert.Equal(t, 3, v)
+}
+
+func TestCache_GetError(t *testing.T) {
+	c := NewCache(time.Minute, 0)
+	_, err := c.Get("symbols", func() (interface{}, error) {
+		return nil, errors.New("unavailable")
+	})
+	assert.EqualError(t, err, "unavailable")
+
+	v, err := c.Get("symbols", func() (interface{}, error) {
+		return "ok", nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "ok", v)
+}
+
+func TestCache_Refresh(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1516536000, 0)}
+	c := NewCache(time.Minute, 30*time.Second)
+	c.Clock = clock.Now
+
+	var loads int32
+	reloaded := make(chan struct{}, 1)
+	load := func() (interface{}, error) {
+		n := atomic.AddInt32(&loads, 1)
+		if n > 1 {
+			reloaded <- struct{}{}
+		}
+		return n, nil
+	}
+
+	c.Get("symbols", load)
+	clock.Advance(40 * time.Second)
+	v, _ := c.Get("symbols", load)
+	assert.Equal(t, int32(1), v)
+
+	select {
+	case <-reloaded:
+	case <-time.After(time.Second):
+		t.Fatal("cache was not refreshed in the background")
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	v, _ = c.Get("symbols", load)
+	assert.Equal(t, int32(2), v)
+}
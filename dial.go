@@ -0,0 +1,48 @@
+package x
+
+import (
+	"context"
+	"errors"
+	"github.com/gorilla/websocket"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ErrNotConnected is returned when a websocket message is sent while there is no connection.
+var ErrNotConnected = errors.New("Websocket is not connected")
+
+// DialWebSocket connects to url, giving up when ctx is done. The deadline of ctx also bounds the handshake.
+func DialWebSocket(ctx context.Context, url string) (*websocket.Conn, error) {
+	dialer := &websocket.Dialer{
+		Proxy: http.ProxyFromEnvironment,
+		NetDial: func(network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.HandshakeTimeout = time.Until(deadline)
+	}
+
+	type result struct {
+		conn *websocket.Conn
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		conn, _, err := dialer.Dial(url, nil)
+		results <- result{conn: conn, err: err}
+	}()
+
+	select {
+	case r := <-results:
+		return r.conn, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-results; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
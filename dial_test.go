@@ -0,0 +1,42 @@
+package x
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDialWebSocket(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	conn, err := DialWebSocket(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"))
+	assert.Nil(t, err)
+	conn.Close()
+}
+
+func TestDialWebSocket_Timeout(t *testing.T) {
+	// a listener which never answers the handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = DialWebSocket(ctx, "ws://"+listener.Addr().String())
+	assert.NotNil(t, err)
+	assert.True(t, time.Since(start) < time.Second)
+}
@@ -0,0 +1,60 @@
+package x
+
+import "sync"
+
+// Dispatcher runs funcs one at a time on its own goroutine, in the order they were added. Clients hand their listener
+// callbacks to it so that the goroutine reading the connection never waits for a callback, which leaves callbacks free
+// to send requests and wait for their responses.
+type Dispatcher struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	funcs  []func()
+	closed bool
+}
+
+// NewDispatcher starts the goroutine of a new dispatcher, it runs until Close.
+func NewDispatcher() *Dispatcher {
+	d := &Dispatcher{}
+	d.cond = sync.NewCond(&d.mutex)
+	go d.run()
+	return d
+}
+
+// Add queues f without waiting, funcs added after Close are dropped.
+func (d *Dispatcher) Add(f func()) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.closed {
+		return
+	}
+	d.funcs = append(d.funcs, f)
+	d.cond.Signal()
+}
+
+// Close drops the queued funcs and stops the goroutine once the running one returns.
+func (d *Dispatcher) Close() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.closed = true
+	d.funcs = nil
+	d.cond.Signal()
+}
+
+func (d *Dispatcher) run() {
+	for {
+		d.mutex.Lock()
+		for len(d.funcs) == 0 && !d.closed {
+			d.cond.Wait()
+		}
+		if d.closed {
+			d.mutex.Unlock()
+			return
+		}
+		f := d.funcs[0]
+		d.funcs[0] = nil
+		d.funcs = d.funcs[1:]
+		d.mutex.Unlock()
+
+		f()
+	}
+}
@@ -0,0 +1,49 @@
+package x
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDispatcher_Order(t *testing.T) {
+	d := NewDispatcher()
+	defer d.Close()
+
+	values := make(chan int, 3)
+	for i := 1; i <= 3; i++ {
+		i := i
+		d.Add(func() { values <- i })
+	}
+	assert.Equal(t, []int{1, 2, 3}, []int{<-values, <-values, <-values})
+}
+
+func TestDispatcher_AddDoesNotWait(t *testing.T) {
+	d := NewDispatcher()
+	defer d.Close()
+
+	release := make(chan struct{})
+	done := make(chan struct{})
+	d.Add(func() { <-release })
+	d.Add(func() { close(done) })
+	close(release)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("queued func did not run")
+	}
+}
+
+func TestDispatcher_Close(t *testing.T) {
+	d := NewDispatcher()
+	ran := make(chan struct{}, 1)
+	d.Close()
+	d.Add(func() { ran <- struct{}{} })
+
+	select {
+	case <-ran:
+		t.Fatal("func added after Close ran")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -1,6 +1,9 @@
 package x
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+)
 
 type ApiError struct {
 	Code    ApiCode
@@ -14,7 +17,7 @@ func (e *ApiError) Error() string {
 type ApiCode uint16
 
 const (
-	OK                     ApiCode = iota
+	OK ApiCode = iota
 	GeneralError
 	InvalidArgument
 	InternalError
@@ -36,4 +39,46 @@ const (
 	UserNotFound
 	InvalidIpAddress
 	TradeRecordNotFound
+	InvalidOrderState
+	InvalidSymbol
+	TradingDisabled
+	InvalidAddress
+	OperationForbidden
 )
+
+var apiCodeNames = [...]string{
+	OK:                     "OK",
+	GeneralError:           "GeneralError",
+	InvalidArgument:        "InvalidArgument",
+	InternalError:          "InternalError",
+	Maintained:             "Maintained",
+	Unavailable:            "Unavailable",
+	RequestTimeExpired:     "RequestTimeExpired",
+	TooFrequent:            "TooFrequent",
+	Unknown:                "Unknown",
+	AuthenticationFailed:   "AuthenticationFailed",
+	FundPasswordLocked:     "FundPasswordLocked",
+	IncorrectFundPassword:  "IncorrectFundPassword",
+	AuthenticationAuditing: "AuthenticationAuditing",
+	EmptyChannel:           "EmptyChannel",
+	EmptyEvent:             "EmptyEvent",
+	InsufficientFund:       "InsufficientFund",
+	OrderNotFound:          "OrderNotFound",
+	InvalidPrice:           "InvalidPrice",
+	InvalidAmount:          "InvalidAmount",
+	UserNotFound:           "UserNotFound",
+	InvalidIpAddress:       "InvalidIpAddress",
+	TradeRecordNotFound:    "TradeRecordNotFound",
+	InvalidOrderState:      "InvalidOrderState",
+	InvalidSymbol:          "InvalidSymbol",
+	TradingDisabled:        "TradingDisabled",
+	InvalidAddress:         "InvalidAddress",
+	OperationForbidden:     "OperationForbidden",
+}
+
+func (c ApiCode) String() string {
+	if int(c) < len(apiCodeNames) {
+		return apiCodeNames[c]
+	}
+	return "ApiCode(" + strconv.Itoa(int(c)) + ")"
+}
@@ -0,0 +1,16 @@
+package x
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestApiCode_String(t *testing.T) {
+	assert.Equal(t, "OK", OK.String())
+	assert.Equal(t, "TradeRecordNotFound", TradeRecordNotFound.String())
+	assert.Equal(t, "OperationForbidden", OperationForbidden.String())
+	assert.Equal(t, "ApiCode(1000)", ApiCode(1000).String())
+	for c := OK; c <= OperationForbidden; c++ {
+		assert.NotEmpty(t, apiCodeNames[c])
+	}
+}
@@ -1,14 +1,44 @@
 package x
 
 import (
-	"net/url"
+	"bytes"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 )
 
 type HttpClient struct {
 	*http.Client
+	Interceptors []Interceptor
+}
+
+// Call describes a single request made by HttpClient. The response fields are filled once the request completes.
+type Call struct {
+	Method   string
+	Url      string
+	Query    Query
+	Body     []byte
+	Status   int
+	Response []byte
+	Code     ApiCode
+	Latency  time.Duration
+	extract  func([]byte) error
+}
+
+type Handler func(call *Call) error
+
+// Interceptor wraps every call made by HttpClient, it must invoke next to let the call proceed.
+type Interceptor func(call *Call, next Handler) error
+
+// SecretParams lists the query parameters which are masked by Query.Redact.
+var SecretParams = map[string]bool{
+	"accesskey":   true,
+	"sign":        true,
+	"AccessKeyId": true,
+	"Signature":   true,
+	"safePwd":     true,
 }
 
 type Query map[string]interface{}
@@ -43,21 +73,101 @@ func (q Query) Encode() Query {
 			s = strconv.FormatFloat(float64(x), 'f', -1, 64)
 		case string:
 			s = v.(string)
+		case TradeType:
+			s = strconv.FormatInt(int64(x), 10)
 		}
 		q[k] = s
 	}
 	return q
 }
 
+func (q Query) Clone() Query {
+	r := make(Query, len(q))
+	for k, v := range q {
+		r[k] = v
+	}
+	return r
+}
+
+func (q Query) Redact() Query {
+	r := Query{}
+	for k, v := range q {
+		if SecretParams[k] {
+			r[k] = "***"
+		} else {
+			r[k] = v
+		}
+	}
+	return r
+}
+
+// DoGet returns the response whenever one arrived, together with the error of the call, like a 429 turned into TooFrequent.
 func (c *HttpClient) DoGet(url string, query Query) (*Response, error) {
-	resp, err := c.Get(BuildUrl(url, query).String())
-	var r Response
-	if err == nil {
-		r = Response(*resp)
-		return &r, nil
+	call := &Call{Method: http.MethodGet, Url: url, Query: query}
+	err := c.do(call)
+	if call.Status == 0 {
+		return nil, err
 	}
 
-	return nil, err
+	return &Response{StatusCode: call.Status, Body: ioutil.NopCloser(bytes.NewReader(call.Response))}, err
+}
+
+// Invoke sends a request through the interceptors and returns the whole response body, extract turns an api level failure into an error.
+func (c *HttpClient) Invoke(method string, url string, query Query, body []byte, extract func([]byte) error) ([]byte, error) {
+	call := &Call{Method: method, Url: url, Query: query, Body: body, extract: extract}
+	err := c.do(call)
+	return call.Response, err
+}
+
+func (c *HttpClient) do(call *Call) error {
+	handler := c.send
+	for i := len(c.Interceptors) - 1; i >= 0; i-- {
+		interceptor, next := c.Interceptors[i], handler
+		handler = func(call *Call) error {
+			return interceptor(call, next)
+		}
+	}
+	return handler(call)
+}
+
+func (c *HttpClient) send(call *Call) error {
+	req, err := http.NewRequest(call.Method, BuildUrl(call.Url, call.Query).String(), bytes.NewReader(call.Body))
+	if err != nil {
+		return err
+	}
+	if call.Body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	start := time.Now()
+	resp, err := c.Do(req)
+	if err != nil {
+		call.Latency = time.Since(start)
+		return err
+	}
+	defer resp.Body.Close()
+
+	call.Response, err = ioutil.ReadAll(resp.Body)
+	call.Latency = time.Since(start)
+	call.Status = resp.StatusCode
+	if err != nil {
+		return err
+	}
+
+	if call.Status == http.StatusTooManyRequests {
+		call.Code = TooFrequent
+		return &ApiError{Code: TooFrequent, Message: resp.Status}
+	}
+
+	if call.extract != nil {
+		if err := call.extract(call.Response); err != nil {
+			if e, ok := err.(*ApiError); ok {
+				call.Code = e.Code
+			}
+			return err
+		}
+	}
+	return nil
 }
 
 type Response http.Response
@@ -0,0 +1,122 @@
+package x
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHttpClient_Invoke(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "btc_usdt", r.URL.Query().Get("market"))
+		w.Write([]byte(`{"code":3001}`))
+	}))
+	defer server.Close()
+
+	var order []string
+	trace := func(name string) Interceptor {
+		return func(call *Call, next Handler) error {
+			order = append(order, name+">")
+			err := next(call)
+			order = append(order, "<"+name)
+			return err
+		}
+	}
+
+	c := &HttpClient{Client: server.Client(), Interceptors: []Interceptor{trace("a"), trace("b")}}
+	bytes, err := c.Invoke(http.MethodGet, server.URL, Query{"market": "btc_usdt"}, nil, func(value []byte) error {
+		return &ApiError{Code: OrderNotFound}
+	})
+	assert.Equal(t, `{"code":3001}`, string(bytes))
+	assert.Equal(t, OrderNotFound, err.(*ApiError).Code)
+	assert.Equal(t, []string{"a>", "b>", "<b", "<a"}, order)
+}
+
+func TestHttpClient_InterceptorCanShortCircuit(t *testing.T) {
+	c := &HttpClient{Client: http.DefaultClient, Interceptors: []Interceptor{func(call *Call, next Handler) error {
+		return errors.New("blocked")
+	}}}
+	_, err := c.Invoke(http.MethodGet, "http://127.0.0.1:0", Query{}, nil, nil)
+	assert.EqualError(t, err, "blocked")
+}
+
+func TestHttpClient_DoGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong"))
+	}))
+	defer server.Close()
+
+	var seen *Call
+	c := &HttpClient{Client: server.Client(), Interceptors: []Interceptor{func(call *Call, next Handler) error {
+		seen = call
+		return next(call)
+	}}}
+	resp, err := c.DoGet(server.URL, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "pong", string(resp.ReadBytes()))
+	assert.Equal(t, 200, seen.Status)
+	assert.Equal(t, 4, len(seen.Response))
+}
+
+func TestHttpClient_DoGetTooManyRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte("slow down"))
+	}))
+	defer server.Close()
+
+	c := &HttpClient{Client: server.Client()}
+	resp, err := c.DoGet(server.URL, nil)
+	assert.Equal(t, TooFrequent, err.(*ApiError).Code)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "slow down", string(resp.ReadBytes()))
+}
+
+func TestQuery_Redact(t *testing.T) {
+	q := Query{"accesskey": "key", "sign": "abc", "Signature": "def", "AccessKeyId": "id", "market": "btc_usdt"}
+	r := q.Redact()
+	assert.Equal(t, Query{"accesskey": "***", "sign": "***", "Signature": "***", "AccessKeyId": "***", "market": "btc_usdt"}, r)
+	assert.Equal(t, "key", q["accesskey"])
+}
+
+func TestQuery_Clone(t *testing.T) {
+	q := Query{"market": "btc_usdt"}
+	r := q.Clone()
+	r["sign"] = "abc"
+	assert.Equal(t, Query{"market": "btc_usdt"}, q)
+}
+
+func TestRedactPayload(t *testing.T) {
+	payload := []byte(`{"accesskey":"key","channel":"getaccountinfo","no":"1","sign":"abc"}`)
+	assert.Equal(t, `{"accesskey":"***","channel":"getaccountinfo","no":"1","sign":"***"}`, string(RedactPayload(payload)))
+	assert.Equal(t, `{"accesskey":"key","channel":"getaccountinfo","no":"1","sign":"abc"}`, string(payload))
+	assert.Equal(t, `{"op":"sub","topic":"orders.btcusdt"}`, string(RedactPayload([]byte(`{"op":"sub","topic":"orders.btcusdt"}`))))
+}
+
+func TestLogInterceptor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	var buffer bytes.Buffer
+	c := &HttpClient{Client: server.Client(), Interceptors: []Interceptor{LogInterceptor(log.New(&buffer, "", 0))}}
+	c.Invoke(http.MethodGet, server.URL+"/order", Query{"accesskey": "secret", "currency": "btc_usdt"}, nil, nil)
+
+	line := buffer.String()
+	assert.True(t, strings.HasPrefix(line, "method=GET endpoint="+server.URL+"/order "), line)
+	assert.Contains(t, line, `params="accesskey=%2A%2A%2A&currency=btc_usdt"`)
+	assert.Contains(t, line, "status=200 code=OK")
+	assert.Contains(t, line, "bytes=2")
+	assert.NotContains(t, line, "secret")
+}
+
+func TestQuery_Encode(t *testing.T) {
+	q := Query{"pageIndex": uint64(1), "price": 0.01, "tradeType": Buy, "all": All, "currency": "btc_usdt"}.Encode()
+	assert.Equal(t, Query{"pageIndex": "1", "price": "0.01", "tradeType": "1", "all": "-1", "currency": "btc_usdt"}, q)
+}
@@ -0,0 +1,145 @@
+package zb
+
+import (
+	. "github.com/berryland/x"
+	json "github.com/buger/jsonparser"
+	"strconv"
+)
+
+var depositStates = map[string]DepositStatus{
+	"unknown":    DepositConfirming,
+	"confirming": DepositConfirming,
+	"confirmed":  DepositSucceeded,
+	"safe":       DepositSucceeded,
+	"orphan":     DepositFailed,
+}
+
+var withdrawStates = map[string]WithdrawStatus{
+	"submitted":       WithdrawSubmitted,
+	"reexamine":       WithdrawSubmitted,
+	"canceled":        WithdrawCancelled,
+	"pass":            WithdrawProcessing,
+	"reject":          WithdrawFailed,
+	"pre-transfer":    WithdrawProcessing,
+	"wallet-transfer": WithdrawProcessing,
+	"wallet-reject":   WithdrawFailed,
+	"confirmed":       WithdrawSucceeded,
+	"confirm-error":   WithdrawFailed,
+	"repealed":        WithdrawFailed,
+}
+
+func (c *HuobiHttpClient) GetDepositAddress(currency string, accessKey, secretKey string) (string, error) {
+	bytes, err := c.getTrade("dw/deposit-virtual/addresses", Query{"currency": currency}, accessKey, secretKey)
+	if err != nil {
+		return "", err
+	}
+
+	return getString(bytes, "data"), nil
+}
+
+// Withdraw submits a withdrawal to address and returns its id, tag is the memo required by some currencies and may be empty.
+func (c *HuobiHttpClient) Withdraw(currency string, address string, tag string, amount, fee float64, accessKey, secretKey string) (uint64, error) {
+	body := map[string]string{
+		"currency": currency,
+		"address":  address,
+		"amount":   strconv.FormatFloat(amount, 'f', -1, 64),
+		"fee":      strconv.FormatFloat(fee, 'f', -1, 64),
+	}
+	if tag != "" {
+		body["addr-tag"] = tag
+	}
+	bytes, err := c.postTrade("dw/withdraw/api/create", body, accessKey, secretKey)
+	if err != nil {
+		return 0, err
+	}
+
+	return getUint(bytes, "data"), nil
+}
+
+func (c *HuobiHttpClient) CancelWithdraw(id uint64, accessKey, secretKey string) error {
+	_, err := c.postTrade("dw/withdraw-virtual/"+strconv.FormatUint(id, 10)+"/cancel", map[string]string{}, accessKey, secretKey)
+	return err
+}
+
+// GetDepositRecords returns up to size deposits of currency, starting from the record with id from when it is not zero.
+func (c *HuobiHttpClient) GetDepositRecords(currency string, from uint64, size uint16, accessKey, secretKey string) ([]DepositRecord, error) {
+	bytes, err := c.getDepositWithdraw("deposit", currency, from, size, accessKey, secretKey)
+	if err != nil {
+		return []DepositRecord{}, err
+	}
+
+	var records []DepositRecord
+	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+		records = append(records, DepositRecord{
+			Id:       getUint(value, "id"),
+			Currency: ParseCurrency(getString(value, "currency")),
+			Address:  getString(value, "address"),
+			Amount:   getFloat(value, "amount"),
+			TxId:     getString(value, "tx-hash"),
+			Status:   depositStates[getString(value, "state")],
+			Time:     getUint(value, "created-at"),
+		})
+	}, "data")
+
+	return records, nil
+}
+
+// GetWithdrawRecords returns up to size withdrawals of currency, starting from the record with id from when it is not zero.
+func (c *HuobiHttpClient) GetWithdrawRecords(currency string, from uint64, size uint16, accessKey, secretKey string) ([]WithdrawRecord, error) {
+	bytes, err := c.getDepositWithdraw("withdraw", currency, from, size, accessKey, secretKey)
+	if err != nil {
+		return []WithdrawRecord{}, err
+	}
+
+	var records []WithdrawRecord
+	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+		records = append(records, WithdrawRecord{
+			Id:       getUint(value, "id"),
+			Currency: ParseCurrency(getString(value, "currency")),
+			Address:  getString(value, "address"),
+			Amount:   getFloat(value, "amount"),
+			Fee:      getFloat(value, "fee"),
+			TxId:     getString(value, "tx-hash"),
+			Status:   withdrawStates[getString(value, "state")],
+			Time:     getUint(value, "created-at"),
+		})
+	}, "data")
+
+	return records, nil
+}
+
+// TransferToMargin moves amount of currency from the spot account to the margin account of symbol and returns the transfer id.
+func (c *HuobiHttpClient) TransferToMargin(symbol string, currency string, amount float64, accessKey, secretKey string) (uint64, error) {
+	return c.transfer("dw/transfer-in/margin", symbol, currency, amount, accessKey, secretKey)
+}
+
+// TransferFromMargin moves amount of currency from the margin account of symbol back to the spot account.
+func (c *HuobiHttpClient) TransferFromMargin(symbol string, currency string, amount float64, accessKey, secretKey string) (uint64, error) {
+	return c.transfer("dw/transfer-out/margin", symbol, currency, amount, accessKey, secretKey)
+}
+
+func (c *HuobiHttpClient) transfer(path string, symbol string, currency string, amount float64, accessKey, secretKey string) (uint64, error) {
+	body := map[string]string{
+		"symbol":   symbol,
+		"currency": currency,
+		"amount":   strconv.FormatFloat(amount, 'f', -1, 64),
+	}
+	bytes, err := c.postTrade(path, body, accessKey, secretKey)
+	if err != nil {
+		return 0, err
+	}
+
+	return getUint(bytes, "data"), nil
+}
+
+func (c *HuobiHttpClient) getDepositWithdraw(recordType string, currency string, from uint64, size uint16, accessKey, secretKey string) ([]byte, error) {
+	q := Query{
+		"currency": currency,
+		"type":     recordType,
+		"size":     size,
+	}
+	if from > 0 {
+		q["from"] = from
+	}
+	return c.getTrade("query/deposit-withdraw", q, accessKey, secretKey)
+}
@@ -0,0 +1,101 @@
+package zb
+
+import (
+	. "github.com/berryland/x"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestHuobiHttpClient_GetDepositAddress(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /v1/dw/deposit-virtual/addresses": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "eth", r.URL.Query().Get("currency"))
+			w.Write([]byte(`{"status":"ok","data":"0x6b2a2c1e"}`))
+		},
+	}))
+	defer shutdown()
+
+	address, err := c.GetDepositAddress("eth", testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "0x6b2a2c1e", address)
+}
+
+func TestHuobiHttpClient_Withdraw(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"POST /v1/dw/withdraw/api/create": func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.JSONEq(t, `{"currency":"xrp","address":"rAddress","addr-tag":"1234","amount":"20.5","fee":"0.1"}`, string(body))
+			w.Write([]byte(`{"status":"ok","data":700}`))
+		},
+		"POST /v1/dw/withdraw-virtual/700/cancel": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"status":"ok","data":700}`))
+		},
+	}))
+	defer shutdown()
+
+	id, err := c.Withdraw("xrp", "rAddress", "1234", 20.5, 0.1, testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(700), id)
+	assert.Nil(t, c.CancelWithdraw(id, testAccessKey, testSecretKey))
+}
+
+func TestHuobiHttpClient_GetDepositRecords(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /v1/query/deposit-withdraw": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			assert.Equal(t, "deposit", q.Get("type"))
+			assert.Equal(t, "btc", q.Get("currency"))
+			assert.Equal(t, "100", q.Get("from"))
+			assert.Equal(t, "10", q.Get("size"))
+			w.Write([]byte(`{"status":"ok","data":[{"id":101,"type":"deposit","currency":"btc","tx-hash":"a1b2","amount":0.5,"address":"1Address","address-tag":"","fee":0,"state":"safe","created-at":1510912472199,"updated-at":1511145876575},{"id":102,"type":"deposit","currency":"btc","tx-hash":"c3d4","amount":"1.2","address":"1Address","state":"confirming","created-at":1510912472200}]}`))
+		},
+	}))
+	defer shutdown()
+
+	records, err := c.GetDepositRecords("btc", 100, 10, testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []DepositRecord{
+		{Id: 101, Currency: ParseCurrency("btc"), Address: "1Address", Amount: 0.5, TxId: "a1b2", Status: DepositSucceeded, Time: 1510912472199},
+		{Id: 102, Currency: ParseCurrency("btc"), Address: "1Address", Amount: 1.2, TxId: "c3d4", Status: DepositConfirming, Time: 1510912472200},
+	}, records)
+}
+
+func TestHuobiHttpClient_GetWithdrawRecords(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /v1/query/deposit-withdraw": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "withdraw", r.URL.Query().Get("type"))
+			assert.Empty(t, r.URL.Query().Get("from"))
+			w.Write([]byte(`{"status":"ok","data":[{"id":700,"type":"withdraw","currency":"xrp","tx-hash":"e5f6","amount":20.5,"address":"rAddress","address-tag":"1234","fee":0.1,"state":"wallet-transfer","created-at":1510912472199}]}`))
+		},
+	}))
+	defer shutdown()
+
+	records, err := c.GetWithdrawRecords("xrp", 0, 10, testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []WithdrawRecord{
+		{Id: 700, Currency: ParseCurrency("xrp"), Address: "rAddress", Amount: 20.5, Fee: 0.1, TxId: "e5f6", Status: WithdrawProcessing, Time: 1510912472199},
+	}, records)
+}
+
+func TestHuobiHttpClient_TransferToMargin(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"POST /v1/dw/transfer-in/margin": func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.JSONEq(t, `{"symbol":"btcusdt","currency":"usdt","amount":"100"}`, string(body))
+			w.Write([]byte(`{"status":"ok","data":1000}`))
+		},
+		"POST /v1/dw/transfer-out/margin": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"status":"error","err-code":"account-transfer-balance-insufficient-error","err-msg":"trade account balance is not enough"}`))
+		},
+	}))
+	defer shutdown()
+
+	id, err := c.TransferToMargin("btcusdt", "usdt", 100, testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1000), id)
+
+	_, err = c.TransferFromMargin("btcusdt", "usdt", 100, testAccessKey, testSecretKey)
+	assert.Equal(t, InsufficientFund, err.(*ApiError).Code)
+}
@@ -4,6 +4,8 @@ import (
 	. "github.com/berryland/x"
 	json "github.com/buger/jsonparser"
 	"net/http"
+	"sort"
+	"sync"
 )
 
 const (
@@ -12,17 +14,85 @@ const (
 )
 
 var ApiCodes = map[string]ApiCode{
-	"bad-argument": InvalidArgument,
+	"bad-argument":                                InvalidArgument,
+	"bad-request":                                 InvalidArgument,
+	"invalid-parameter":                           InvalidArgument,
+	"invalid-command":                             InvalidArgument,
+	"base-date-error":                             InvalidArgument,
+	"base-currency-error":                         InvalidArgument,
+	"base-symbol-error":                           InvalidSymbol,
+	"invalid-symbol":                              InvalidSymbol,
+	"require-symbol":                              InvalidSymbol,
+	"base-symbol-trade-disabled":                  TradingDisabled,
+	"base-operation-forbidden":                    OperationForbidden,
+	"api-not-support-temp-addr":                   OperationForbidden,
+	"base-system-error":                           InternalError,
+	"gateway-internal-error":                      InternalError,
+	"internal-error":                              InternalError,
+	"system-maintenance":                          Maintained,
+	"timeout":                                     Unavailable,
+	"order-update-error":                          Unavailable,
+	"login-required":                              AuthenticationFailed,
+	"api-signature-not-valid":                     AuthenticationFailed,
+	"api-signature-check-failed":                  AuthenticationFailed,
+	"account-frozen-balance-insufficient-error":   InsufficientFund,
+	"account-transfer-balance-insufficient-error": InsufficientFund,
+	"account-balance-insufficient-error":          InsufficientFund,
+	"order-accountbalance-error":                  InsufficientFund,
+	"dw-insufficient-balance":                     InsufficientFund,
+	"account-get-accounts-inexistent-error":       UserNotFound,
+	"account-account-id-inexistent":               UserNotFound,
+	"order-limitorder-price-error":                InvalidPrice,
+	"order-limitorder-price-min-error":            InvalidPrice,
+	"order-limitorder-price-max-error":            InvalidPrice,
+	"order-orderprice-precision-error":            InvalidPrice,
+	"order-limitorder-amount-min-error":           InvalidAmount,
+	"order-limitorder-amount-max-error":           InvalidAmount,
+	"order-marketorder-amount-min-error":          InvalidAmount,
+	"order-marketorder-amount-buy-max-error":      InvalidAmount,
+	"order-marketorder-amount-sell-max-error":     InvalidAmount,
+	"order-orderamount-precision-error":           InvalidAmount,
+	"order-value-min-error":                       InvalidAmount,
+	"order-holding-limit-failed":                  InvalidAmount,
+	"invalid-amount":                              InvalidAmount,
+	"dw-withdraw-min-limit":                       InvalidAmount,
+	"order-orderstate-error":                      InvalidOrderState,
+	"order-queryorder-invalid":                    OrderNotFound,
+	"base-record-invalid":                         OrderNotFound,
+	"invalid-address":                             InvalidAddress,
+	"base-currency-chain-error":                   InvalidAddress,
 }
 
+type DepthStep string
+
+const (
+	Step0 DepthStep = "step0"
+	Step1 DepthStep = "step1"
+	Step2 DepthStep = "step2"
+	Step3 DepthStep = "step3"
+	Step4 DepthStep = "step4"
+	Step5 DepthStep = "step5"
+)
+
 type HuobiHttpClient struct {
-	Client *HttpClient
+	Client     *HttpClient
+	Cache      *Cache
+	mutex      sync.Mutex
+	accountIds map[string]uint64
 }
 
+var (
+	_ HttpApiClient  = (*HuobiHttpClient)(nil)
+	_ TradeApiClient = (*HuobiHttpClient)(nil)
+)
+
 func NewHttpClient() *HuobiHttpClient {
 	return &HuobiHttpClient{Client: &HttpClient{Client: &http.Client{}}}
 }
 
+// GetKlines returns up to size of the latest klines in ascending order. The api has no time range parameter, so klines
+// opened before since are dropped from the result, and ErrRangeUnavailable is returned when the latest size klines all
+// opened after since. Use HuobiWebSocketClient.GetKlineRange for older klines.
 func (c *HuobiHttpClient) GetKlines(pair Pair, period string, since uint64, size uint16) ([]Kline, error) {
 	var klines []Kline
 	q := Query{
@@ -30,41 +100,132 @@ func (c *HuobiHttpClient) GetKlines(pair Pair, period string, since uint64, size
 		"period": period,
 		"size":   size,
 	}
-	resp, err := c.Client.DoGet(DataApiUrl+"history/kline", q)
+	bytes, err := c.getData("history/kline", q)
 	if err != nil {
 		return klines, err
 	}
 
-	bytes := resp.ReadBytes()
-	err = extractDataApiError(bytes)
+	count, oldest := 0, uint64(0)
+	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+		kline := marshalKline(value)
+		if count == 0 || kline.Time < oldest {
+			oldest = kline.Time
+		}
+		count++
+		if kline.Time < since {
+			return
+		}
+		klines = append(klines, kline)
+	}, "data")
+	if since > 0 && count == int(size) && oldest > since {
+		return nil, ErrRangeUnavailable
+	}
+
+	sort.Slice(klines, func(i, j int) bool {
+		return klines[i].Time < klines[j].Time
+	})
+	return klines, nil
+}
+
+func (c *HuobiHttpClient) GetTicker(pair Pair) (Ticker, error) {
+	q := Query{
+		"symbol": parseSymbol(pair),
+	}
+	bytes, err := c.getData("detail/merged", q)
 	if err != nil {
-		return klines, err
+		return Ticker{}, err
+	}
+
+	time, _ := json.GetInt(bytes, "ts")
+	ticker, _, _, _ := json.Get(bytes, "tick")
+	close, _ := json.GetFloat(ticker, "close")
+	high, _ := json.GetFloat(ticker, "high")
+	low, _ := json.GetFloat(ticker, "low")
+	amount, _ := json.GetFloat(ticker, "amount")
+	ask, _ := json.GetFloat(ticker, "ask", "[0]")
+	bid, _ := json.GetFloat(ticker, "bid", "[0]")
+
+	return Ticker{Amount: amount, High: high, Low: low, Last: close, Bid: bid, Ask: ask, Time: uint64(time)}, nil
+}
+
+func (c *HuobiHttpClient) getData(path string, query Query) ([]byte, error) {
+	return c.Client.Invoke(http.MethodGet, DataApiUrl+path, query, nil, extractDataApiError)
+}
+
+func (c *HuobiHttpClient) GetAllTickers() (map[Pair]Ticker, error) {
+	tickers := map[Pair]Ticker{}
+	symbols, err := c.GetSymbols()
+	if err != nil {
+		return tickers, err
+	}
+
+	bytes, err := c.getData("tickers", Query{})
+	if err != nil {
+		return tickers, err
 	}
 
 	time, _ := json.GetInt(bytes, "ts")
 	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
-		open, _ := json.GetFloat(value, "open")
+		symbol, _ := json.GetString(value, "symbol")
+		config, ok := symbols[symbol]
+		if !ok {
+			return
+		}
+
+		close, _ := json.GetFloat(value, "close")
 		high, _ := json.GetFloat(value, "high")
 		low, _ := json.GetFloat(value, "low")
-		close, _ := json.GetFloat(value, "close")
 		amount, _ := json.GetFloat(value, "amount")
-		klines = append(klines, Kline{Time: uint64(time), Open: open, High: high, Low: low, Close: close, Amount: amount})
+		tickers[config.Pair] = Ticker{Amount: amount, High: high, Low: low, Last: close, Time: uint64(time)}
 	}, "data")
+	return tickers, nil
+}
 
-	return klines, nil
+func (c *HuobiHttpClient) GetDepth(pair Pair, step DepthStep) (Depth, error) {
+	q := Query{
+		"symbol": parseSymbol(pair),
+		"type":   string(step),
+	}
+	bytes, err := c.getData("depth", q)
+	if err != nil {
+		return Depth{}, err
+	}
+
+	time, _ := json.GetInt(bytes, "ts")
+	asks, bids := ParseDepthEntries(bytes, "tick", "asks"), ParseDepthEntries(bytes, "tick", "bids")
+	return Depth{Asks: asks, Bids: bids, Time: uint64(time)}, nil
 }
 
-func (c *HuobiHttpClient) GetTicker(pair Pair) (Ticker, error) {
+// GetTrades returns the latest trades of pair.
+func (c *HuobiHttpClient) GetTrades(pair Pair) ([]Trade, error) {
+	bytes, err := c.getData("trade", Query{"symbol": parseSymbol(pair)})
+	if err != nil {
+		return []Trade{}, err
+	}
+
+	return marshalTrades(bytes, "tick", "data"), nil
+}
+
+func (c *HuobiHttpClient) GetHistoryTrades(pair Pair, size uint16) ([]Trade, error) {
 	q := Query{
 		"symbol": parseSymbol(pair),
+		"size":   size,
 	}
-	resp, err := c.Client.DoGet(DataApiUrl+"detail/merged", q)
+	bytes, err := c.getData("history/trade", q)
 	if err != nil {
-		return Ticker{}, err
+		return []Trade{}, err
 	}
 
-	bytes := resp.ReadBytes()
-	err = extractDataApiError(bytes)
+	var trades []Trade
+	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+		trades = append(trades, marshalTrades(value, "data")...)
+	}, "data")
+	return trades, nil
+}
+
+// GetDetail returns the 24 hours statistics of pair, the detail carries no bid or ask.
+func (c *HuobiHttpClient) GetDetail(pair Pair) (Ticker, error) {
+	bytes, err := c.getData("detail", Query{"symbol": parseSymbol(pair)})
 	if err != nil {
 		return Ticker{}, err
 	}
@@ -75,10 +236,87 @@ func (c *HuobiHttpClient) GetTicker(pair Pair) (Ticker, error) {
 	high, _ := json.GetFloat(ticker, "high")
 	low, _ := json.GetFloat(ticker, "low")
 	amount, _ := json.GetFloat(ticker, "amount")
-	ask, _ := json.GetFloat(ticker, "ask", "[0]")
-	bid, _ := json.GetFloat(ticker, "bid", "[0]")
+	return Ticker{Amount: amount, High: high, Low: low, Last: close, Time: uint64(time)}, nil
+}
 
-	return Ticker{Amount: amount, High: high, Low: low, Last: close, Bid: bid, Ask: ask, Time: uint64(time)}, nil
+// GetSymbols returns the configs keyed by Huobi symbols such as btcusdt.
+func (c *HuobiHttpClient) GetSymbols() (map[string]SymbolConfig, error) {
+	if c.Cache == nil {
+		return c.getSymbols()
+	}
+
+	value, err := c.Cache.Get("symbols", func() (interface{}, error) {
+		return c.getSymbols()
+	})
+	if err != nil {
+		return map[string]SymbolConfig{}, err
+	}
+
+	configs := map[string]SymbolConfig{}
+	for k, v := range value.(map[string]SymbolConfig) {
+		configs[k] = v
+	}
+	return configs, nil
+}
+
+func (c *HuobiHttpClient) getSymbols() (map[string]SymbolConfig, error) {
+	configs := map[string]SymbolConfig{}
+	bytes, err := c.getCommon("symbols", Query{})
+	if err != nil {
+		return configs, err
+	}
+
+	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+		symbol, _ := json.GetString(value, "symbol")
+		base, _ := json.GetString(value, "base-currency")
+		quote, _ := json.GetString(value, "quote-currency")
+		amountScale, _ := json.GetInt(value, "amount-precision")
+		priceScale, _ := json.GetInt(value, "price-precision")
+		configs[symbol] = SymbolConfig{Pair: Pair{Base: ParseCurrency(base), Valuation: ParseCurrency(quote)}, AmountScale: byte(amountScale), PriceScale: byte(priceScale)}
+	}, "data")
+	return configs, nil
+}
+
+func (c *HuobiHttpClient) GetCurrencies() ([]Currency, error) {
+	if c.Cache == nil {
+		return c.getCurrencies()
+	}
+
+	value, err := c.Cache.Get("currencys", func() (interface{}, error) {
+		return c.getCurrencies()
+	})
+	if err != nil {
+		return []Currency{}, err
+	}
+	return append([]Currency(nil), value.([]Currency)...), nil
+}
+
+func (c *HuobiHttpClient) getCurrencies() ([]Currency, error) {
+	var currencies []Currency
+	bytes, err := c.getCommon("currencys", Query{})
+	if err != nil {
+		return currencies, err
+	}
+
+	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+		currencies = append(currencies, ParseCurrency(string(value)))
+	}, "data")
+	return currencies, nil
+}
+
+// GetTimestamp returns the server time in milliseconds.
+func (c *HuobiHttpClient) GetTimestamp() (uint64, error) {
+	bytes, err := c.getCommon("timestamp", Query{})
+	if err != nil {
+		return 0, err
+	}
+
+	time, _ := json.GetInt(bytes, "data")
+	return uint64(time), nil
+}
+
+func (c *HuobiHttpClient) getCommon(path string, query Query) ([]byte, error) {
+	return c.Client.Invoke(http.MethodGet, TradeApiUrl+"common/"+path, query, nil, extractDataApiError)
 }
 
 func extractDataApiError(value []byte) error {
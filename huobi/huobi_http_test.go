@@ -1,9 +1,11 @@
 package zb
 
 import (
-	"testing"
 	. "github.com/berryland/x"
+	"github.com/berryland/x/xtest"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
 )
 
 func TestHuobiHttpClient_GetKlines(t *testing.T) {
@@ -17,3 +19,89 @@ func TestHuobiHttpClient_GetTicker(t *testing.T) {
 	assert.Nil(t, err)
 	assert.True(t, ticker.Last > 0)
 }
+
+func newTestHttpClient(handler http.HandlerFunc) (*HuobiHttpClient, func()) {
+	c := NewHttpClient()
+	client, shutdown := xtest.NewHttpServer(handler)
+	c.Client.Client = client
+	return c, shutdown
+}
+
+func TestHuobiHttpClient_GetAllTickers(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/common/symbols":
+			w.Write([]byte(`{"status":"ok","data":[{"base-currency":"btc","quote-currency":"usdt","symbol":"btcusdt"},{"base-currency":"eth","quote-currency":"btc","symbol":"ethbtc"}]}`))
+		case "/market/tickers":
+			w.Write([]byte(`{"status":"ok","ts":1516536000000,"data":[{"open":10000,"close":11000,"low":9000,"high":12000,"amount":12.5,"vol":130000,"count":100,"symbol":"btcusdt"},{"close":0.1,"symbol":"ethbtc"},{"close":1,"symbol":"unknown"}]}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	})
+	defer shutdown()
+
+	tickers, err := c.GetAllTickers()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(tickers))
+	assert.Equal(t, Ticker{Amount: 12.5, Last: 11000, High: 12000, Low: 9000, Time: 1516536000000}, tickers[ParsePair("btc_usdt")])
+	assert.Equal(t, 0.1, tickers[ParsePair("eth_btc")].Last)
+}
+
+func TestHuobiHttpClient_GetKlinesSince(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/market/history/kline", r.URL.Path)
+		assert.Equal(t, "btcusdt", r.URL.Query().Get("symbol"))
+		assert.Equal(t, "1min", r.URL.Query().Get("period"))
+		w.Write([]byte(`{"status":"ok","ch":"market.btcusdt.kline.1min","ts":1516536100000,"data":[` +
+			`{"id":1516536060,"open":11010,"close":11020,"low":11000,"high":11030,"amount":2,"vol":22040,"count":10},` +
+			`{"id":1516536000,"open":11000,"close":11010,"low":10990,"high":11015,"amount":1,"vol":11010,"count":5},` +
+			`{"id":1516535940,"open":10990,"close":11000,"low":10980,"high":11005,"amount":3,"vol":33000,"count":7}]}`))
+	})
+	defer shutdown()
+
+	klines, err := c.GetKlines(ParsePair("btc_usdt"), "1min", 1516536000000, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []Kline{
+		{Open: 11000, Close: 11010, High: 11015, Low: 10990, Amount: 1, Time: 1516536000000},
+		{Open: 11010, Close: 11020, High: 11030, Low: 11000, Amount: 2, Time: 1516536060000},
+	}, klines)
+
+	klines, _ = c.GetKlines(ParsePair("btc_usdt"), "1min", 0, 3)
+	assert.Equal(t, 3, len(klines))
+	assert.Equal(t, uint64(1516535940000), klines[0].Time)
+}
+
+func TestHuobiHttpClient_GetKlinesHistoricRange(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "2", r.URL.Query().Get("size"))
+		w.Write([]byte(`{"status":"ok","ch":"market.btcusdt.kline.1min","ts":1516536100000,"data":[` +
+			`{"id":1516536060,"open":11010,"close":11020,"low":11000,"high":11030,"amount":2},` +
+			`{"id":1516536000,"open":11000,"close":11010,"low":10990,"high":11015,"amount":1}]}`))
+	})
+	defer shutdown()
+
+	f := NewKlineFetcher(c)
+	f.PageSize = 2
+	_, err := f.Fetch(ParsePair("btc_usdt"), "1min", 1483228800000, 1483228800000+60*60000)
+	assert.Equal(t, ErrRangeUnavailable, err)
+}
+
+func TestGetApiCode(t *testing.T) {
+	assert.Equal(t, AuthenticationFailed, getApiCode("api-signature-not-valid"))
+	assert.Equal(t, InvalidAmount, getApiCode("order-limitorder-amount-min-error"))
+	assert.Equal(t, InsufficientFund, getApiCode("account-frozen-balance-insufficient-error"))
+	assert.Equal(t, InvalidOrderState, getApiCode("order-orderstate-error"))
+	assert.Equal(t, InvalidSymbol, getApiCode("base-symbol-error"))
+	assert.Equal(t, Unknown, getApiCode("no-such-error"))
+}
+
+func TestHuobiHttpClient_TooManyRequests(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte("Too Many Requests"))
+	})
+	defer shutdown()
+
+	_, err := c.GetTicker(ParsePair("btc_usdt"))
+	assert.Equal(t, TooFrequent, err.(*ApiError).Code)
+}
@@ -0,0 +1,137 @@
+package zb
+
+import (
+	. "github.com/berryland/x"
+	json "github.com/buger/jsonparser"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var loanStates = map[string]LoanStatus{
+	"created": LoanCreated,
+	"accrual": LoanAccrual,
+	"cleared": LoanCleared,
+	"invalid": LoanInvalid,
+}
+
+var loanStateNames = map[LoanStatus]string{
+	LoanCreated: "created",
+	LoanAccrual: "accrual",
+	LoanCleared: "cleared",
+	LoanInvalid: "invalid",
+}
+
+// ApplyLoan borrows amount of currency into the margin account of symbol and returns the loan order id.
+func (c *HuobiHttpClient) ApplyLoan(symbol string, currency string, amount float64, accessKey, secretKey string) (uint64, error) {
+	body := map[string]string{
+		"symbol":   symbol,
+		"currency": currency,
+		"amount":   strconv.FormatFloat(amount, 'f', -1, 64),
+	}
+	bytes, err := c.postTrade("margin/orders", body, accessKey, secretKey)
+	if err != nil {
+		return 0, err
+	}
+
+	return getUint(bytes, "data"), nil
+}
+
+// RepayLoan repays amount of the loan order id, interest is repaid before the principal.
+func (c *HuobiHttpClient) RepayLoan(id uint64, amount float64, accessKey, secretKey string) error {
+	body := map[string]string{
+		"amount": strconv.FormatFloat(amount, 'f', -1, 64),
+	}
+	_, err := c.postTrade("margin/orders/"+strconv.FormatUint(id, 10)+"/repay", body, accessKey, secretKey)
+	return err
+}
+
+// GetLoanOrders returns up to size loan orders of symbol in the given statuses, starting after the order with id
+// from when it is not zero. All statuses are returned when statuses is empty.
+func (c *HuobiHttpClient) GetLoanOrders(symbol string, statuses []LoanStatus, from uint64, size uint16, accessKey, secretKey string) ([]LoanOrder, error) {
+	q := Query{
+		"symbol": symbol,
+		"size":   size,
+	}
+	if len(statuses) > 0 {
+		var states []string
+		for _, status := range statuses {
+			states = append(states, loanStateNames[status])
+		}
+		q["states"] = strings.Join(states, ",")
+	}
+	if from > 0 {
+		q["from"] = from
+		q["direct"] = "next"
+	}
+	bytes, err := c.getTrade("margin/loan-orders", q, accessKey, secretKey)
+	if err != nil {
+		return []LoanOrder{}, err
+	}
+
+	var orders []LoanOrder
+	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+		orders = append(orders, LoanOrder{
+			Id:              getUint(value, "id"),
+			Symbol:          getString(value, "symbol"),
+			Currency:        ParseCurrency(getString(value, "currency")),
+			Amount:          getFloat(value, "loan-amount"),
+			Balance:         getFloat(value, "loan-balance"),
+			InterestRate:    getFloat(value, "interest-rate"),
+			Interest:        getFloat(value, "interest-amount"),
+			InterestBalance: getFloat(value, "interest-balance"),
+			Status:          loanStates[getString(value, "state")],
+			Time:            getUint(value, "created-at"),
+		})
+	}, "data")
+
+	return orders, nil
+}
+
+// GetMarginAccounts returns the margin account of symbol, or every margin account when symbol is empty.
+func (c *HuobiHttpClient) GetMarginAccounts(symbol string, accessKey, secretKey string) ([]MarginAccount, error) {
+	q := Query{}
+	if symbol != "" {
+		q["symbol"] = symbol
+	}
+	bytes, err := c.getTrade("margin/accounts/balance", q, accessKey, secretKey)
+	if err != nil {
+		return []MarginAccount{}, err
+	}
+
+	var accounts []MarginAccount
+	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+		account := MarginAccount{
+			Id:               getUint(value, "id"),
+			Symbol:           getString(value, "symbol"),
+			RiskRate:         getFloat(value, "risk-rate"),
+			LiquidationPrice: getFloat(value, "fl-price"),
+		}
+
+		indexes := map[string]int{}
+		json.ArrayEach(value, func(value []byte, dataType json.ValueType, offset int, err error) {
+			currency := getString(value, "currency")
+			i, ok := indexes[currency]
+			if !ok {
+				i = len(account.Assets)
+				indexes[currency] = i
+				account.Assets = append(account.Assets, MarginAsset{Currency: ParseCurrency(currency)})
+			}
+
+			balance := getFloat(value, "balance")
+			switch getString(value, "type") {
+			case "trade":
+				account.Assets[i].Available = balance
+			case "frozen":
+				account.Assets[i].Freeze = balance
+			case "loan":
+				account.Assets[i].Loan = math.Abs(balance)
+			case "interest":
+				account.Assets[i].Interest = math.Abs(balance)
+			}
+		}, "list")
+		accounts = append(accounts, account)
+	}, "data")
+
+	return accounts, nil
+}
@@ -0,0 +1,82 @@
+package zb
+
+import (
+	. "github.com/berryland/x"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestHuobiHttpClient_ApplyLoan(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"POST /v1/margin/orders": func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.JSONEq(t, `{"symbol":"btcusdt","currency":"usdt","amount":"1000"}`, string(body))
+			w.Write([]byte(`{"status":"ok","data":59378}`))
+		},
+		"POST /v1/margin/orders/59378/repay": func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.JSONEq(t, `{"amount":"1000.5"}`, string(body))
+			w.Write([]byte(`{"status":"ok","data":59378}`))
+		},
+	}))
+	defer shutdown()
+
+	id, err := c.ApplyLoan("btcusdt", "usdt", 1000, testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(59378), id)
+	assert.Nil(t, c.RepayLoan(id, 1000.5, testAccessKey, testSecretKey))
+}
+
+func TestHuobiHttpClient_GetLoanOrders(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /v1/margin/loan-orders": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			assert.Equal(t, "btcusdt", q.Get("symbol"))
+			assert.Equal(t, "created,accrual", q.Get("states"))
+			assert.Equal(t, "59000", q.Get("from"))
+			assert.Equal(t, "next", q.Get("direct"))
+			w.Write([]byte(`{"status":"ok","data":[{"id":59378,"user-id":100002,"account-id":100010,"symbol":"btcusdt","currency":"usdt","loan-amount":"1000.000000000000000000","loan-balance":"800.000000000000000000","interest-rate":"0.002000000000000000","interest-amount":"2.000000000000000000","interest-balance":"1.500000000000000000","created-at":1511169724000,"accrued-at":1511169724531,"state":"accrual"}]}`))
+		},
+	}))
+	defer shutdown()
+
+	orders, err := c.GetLoanOrders("btcusdt", []LoanStatus{LoanCreated, LoanAccrual}, 59000, 10, testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []LoanOrder{{
+		Id:              59378,
+		Symbol:          "btcusdt",
+		Currency:        ParseCurrency("usdt"),
+		Amount:          1000,
+		Balance:         800,
+		InterestRate:    0.002,
+		Interest:        2,
+		InterestBalance: 1.5,
+		Status:          LoanAccrual,
+		Time:            1511169724000,
+	}}, orders)
+}
+
+func TestHuobiHttpClient_GetMarginAccounts(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /v1/margin/accounts/balance": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "btcusdt", r.URL.Query().Get("symbol"))
+			w.Write([]byte(`{"status":"ok","data":[{"id":100010,"type":"margin","state":"working","symbol":"btcusdt","fl-price":"8500","fl-type":"safe","risk-rate":"1.52","list":[{"currency":"btc","type":"trade","balance":"0.5"},{"currency":"btc","type":"frozen","balance":"0.1"},{"currency":"usdt","type":"trade","balance":"2000"},{"currency":"usdt","type":"loan","balance":"-1000"},{"currency":"usdt","type":"interest","balance":"-1.5"},{"currency":"usdt","type":"transfer-out-available","balance":"500"}]}]}`))
+		},
+	}))
+	defer shutdown()
+
+	accounts, err := c.GetMarginAccounts("btcusdt", testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []MarginAccount{{
+		Id:               100010,
+		Symbol:           "btcusdt",
+		RiskRate:         1.52,
+		LiquidationPrice: 8500,
+		Assets: []MarginAsset{
+			{Currency: ParseCurrency("btc"), Available: 0.5, Freeze: 0.1},
+			{Currency: ParseCurrency("usdt"), Available: 2000, Loan: 1000, Interest: 1.5},
+		},
+	}}, accounts)
+}
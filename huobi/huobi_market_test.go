@@ -0,0 +1,117 @@
+package zb
+
+import (
+	. "github.com/berryland/x"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHuobiHttpClient_GetDepth(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/market/depth", r.URL.Path)
+		assert.Equal(t, "step1", r.URL.Query().Get("type"))
+		w.Write([]byte(`{"status":"ok","ch":"market.btcusdt.depth.step1","ts":1516536000000,"tick":{"bids":[[11000,0.5],[10990,1]],"asks":[[11010,0.2]],"ts":1516535999000,"version":100}}`))
+	})
+	defer shutdown()
+
+	depth, err := c.GetDepth(ParsePair("btc_usdt"), Step1)
+	assert.Nil(t, err)
+	assert.Equal(t, Depth{Asks: []DepthEntry{{Price: 11010, Amount: 0.2}}, Bids: []DepthEntry{{Price: 11000, Amount: 0.5}, {Price: 10990, Amount: 1}}, Time: 1516536000000}, depth)
+}
+
+func TestHuobiHttpClient_GetTrades(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/market/trade", r.URL.Path)
+		w.Write([]byte(`{"status":"ok","ch":"market.btcusdt.trade.detail","ts":1516536000000,"tick":{"id":600848670,"ts":1516535999000,"data":[{"id":17592256642623,"amount":0.04,"price":11000,"direction":"buy","ts":1516535999000}]}}`))
+	})
+	defer shutdown()
+
+	trades, err := c.GetTrades(ParsePair("btc_usdt"))
+	assert.Nil(t, err)
+	assert.Equal(t, []Trade{{Id: 17592256642623, TradeType: Buy, Price: 11000, Amount: 0.04, Time: 1516535999000}}, trades)
+}
+
+func TestHuobiHttpClient_GetHistoryTrades(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/market/history/trade", r.URL.Path)
+		assert.Equal(t, "2", r.URL.Query().Get("size"))
+		w.Write([]byte(`{"status":"ok","ch":"market.btcusdt.trade.detail","ts":1516536000000,"data":[` +
+			`{"id":2,"ts":1516535999000,"data":[{"id":20,"trade-id":102,"amount":0.1,"price":11000,"direction":"sell","ts":1516535999000}]},` +
+			`{"id":1,"ts":1516535998000,"data":[{"id":10,"trade-id":101,"amount":0.2,"price":10990,"direction":"buy","ts":1516535998000}]}]}`))
+	})
+	defer shutdown()
+
+	trades, err := c.GetHistoryTrades(ParsePair("btc_usdt"), 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []Trade{
+		{Id: 102, TradeType: Sell, Price: 11000, Amount: 0.1, Time: 1516535999000},
+		{Id: 101, TradeType: Buy, Price: 10990, Amount: 0.2, Time: 1516535998000},
+	}, trades)
+}
+
+func TestHuobiHttpClient_GetDetail(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/market/detail", r.URL.Path)
+		w.Write([]byte(`{"status":"ok","ch":"market.btcusdt.detail","ts":1516536000000,"tick":{"amount":100.5,"open":10000,"close":11000,"high":12000,"id":1,"count":10,"low":9000,"vol":1100000}}`))
+	})
+	defer shutdown()
+
+	ticker, err := c.GetDetail(ParsePair("btc_usdt"))
+	assert.Nil(t, err)
+	assert.Equal(t, Ticker{Amount: 100.5, Last: 11000, High: 12000, Low: 9000, Time: 1516536000000}, ticker)
+}
+
+func TestHuobiHttpClient_GetSymbols(t *testing.T) {
+	var hits int32
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/common/symbols", r.URL.Path)
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(`{"status":"ok","data":[{"base-currency":"btc","quote-currency":"usdt","price-precision":2,"amount-precision":4,"symbol-partition":"main","symbol":"btcusdt"}]}`))
+	})
+	defer shutdown()
+	c.Cache = NewCache(time.Minute, 0)
+
+	for i := 0; i < 2; i++ {
+		symbols, err := c.GetSymbols()
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]SymbolConfig{"btcusdt": {Pair: ParsePair("btc_usdt"), AmountScale: 4, PriceScale: 2}}, symbols)
+	}
+	assert.Equal(t, int32(1), hits)
+}
+
+func TestHuobiHttpClient_GetCurrencies(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/common/currencys", r.URL.Path)
+		w.Write([]byte(`{"status":"ok","data":["usdt","btc","eth"]}`))
+	})
+	defer shutdown()
+
+	currencies, err := c.GetCurrencies()
+	assert.Nil(t, err)
+	assert.Equal(t, []Currency{{Symbol: "usdt"}, {Symbol: "btc"}, {Symbol: "eth"}}, currencies)
+}
+
+func TestHuobiHttpClient_GetTimestamp(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/common/timestamp", r.URL.Path)
+		w.Write([]byte(`{"status":"ok","data":1494900087029}`))
+	})
+	defer shutdown()
+
+	timestamp, err := c.GetTimestamp()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1494900087029), timestamp)
+}
+
+func TestHuobiHttpClient_GetDepthError(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"error","err-code":"bad-argument","err-msg":"invalid symbol"}`))
+	})
+	defer shutdown()
+
+	_, err := c.GetDepth(ParsePair("btc_usdt"), Step0)
+	assert.Equal(t, &ApiError{Code: InvalidArgument, Message: "invalid symbol"}, err)
+}
@@ -0,0 +1,53 @@
+package zb
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	. "github.com/berryland/x"
+	"net/url"
+	"strings"
+)
+
+const timestampLayout = "2006-01-02T15:04:05"
+
+type HuobiSigner struct {
+	AccessKey string
+	SecretKey string
+	Clock     Clock
+}
+
+func NewSigner(accessKey, secretKey string) *HuobiSigner {
+	return &HuobiSigner{AccessKey: accessKey, SecretKey: secretKey}
+}
+
+// Sign adds the Signature Version 2 parameters to query, the signature covers method, host, path and every query parameter.
+func (s *HuobiSigner) Sign(method string, rawUrl string, query Query) error {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return err
+	}
+
+	delete(query, "Signature")
+	query["AccessKeyId"] = s.AccessKey
+	query["SignatureMethod"] = "HmacSHA256"
+	query["SignatureVersion"] = "2"
+	query["Timestamp"] = s.Clock.Now().UTC().Format(timestampLayout)
+	query.Encode()
+	query["Signature"] = genSign(s.SecretKey, getPayload(method, u.Host, u.Path, query))
+	return nil
+}
+
+func getPayload(method, host, path string, query Query) string {
+	values := url.Values{}
+	for k, v := range query {
+		values.Set(k, v.(string))
+	}
+	return strings.ToUpper(method) + "\n" + strings.ToLower(host) + "\n" + path + "\n" + strings.Replace(values.Encode(), "+", "%20", -1)
+}
+
+func genSign(secretKey string, payload string) string {
+	h := hmac.New(sha256.New, []byte(secretKey))
+	h.Write([]byte(payload))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,71 @@
+package zb
+
+import (
+	. "github.com/berryland/x"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const (
+	vectorAccessKey = "e2xxxxxx-99xxxxxx-84xxxxxx-7xxxx"
+	vectorSecretKey = "b0xxxxxx-c6xxxxxx-94xxxxxx-dxxxx"
+)
+
+// signVectors were computed with openssl rather than by the signer, e.g. for the first one:
+//
+//	printf '%b' 'GET\napi.huobi.pro\n/v1/order/orders\nAccessKeyId=...&order-id=1234567890' |
+//		openssl dgst -sha256 -hmac b0xxxxxx-c6xxxxxx-94xxxxxx-dxxxx -binary | base64
+var signVectors = []struct {
+	method  string
+	url     string
+	query   Query
+	payload string
+	sign    string
+}{
+	{
+		"GET",
+		"https://api.huobi.pro/v1/order/orders",
+		Query{"order-id": uint64(1234567890)},
+		"GET\napi.huobi.pro\n/v1/order/orders\nAccessKeyId=e2xxxxxx-99xxxxxx-84xxxxxx-7xxxx&SignatureMethod=HmacSHA256&SignatureVersion=2&Timestamp=2017-05-11T15%3A19%3A30&order-id=1234567890",
+		"Nmd8AU8uAe0mkFpxNbiava0aeZzBEtYjCdie1ZYZjoM=",
+	},
+	{
+		"post",
+		"https://API.huobi.pro/v1/order/orders/place",
+		Query{},
+		"POST\napi.huobi.pro\n/v1/order/orders/place\nAccessKeyId=e2xxxxxx-99xxxxxx-84xxxxxx-7xxxx&SignatureMethod=HmacSHA256&SignatureVersion=2&Timestamp=2017-05-11T15%3A19%3A30",
+		"5NjPB1wj1lHSZO0PkwvX5X7fuOi2DHrI8Y/jS1nbDvQ=",
+	},
+	{
+		"GET",
+		"https://api.huobi.pro/v1/order/orders",
+		Query{"symbol": "btcusdt", "states": "filled,canceled", "start-date": "2018-01-01"},
+		"GET\napi.huobi.pro\n/v1/order/orders\nAccessKeyId=e2xxxxxx-99xxxxxx-84xxxxxx-7xxxx&SignatureMethod=HmacSHA256&SignatureVersion=2&Timestamp=2017-05-11T15%3A19%3A30&start-date=2018-01-01&states=filled%2Ccanceled&symbol=btcusdt",
+		"gGtSvsUNKr6E1y78pX90CVum27/l1AEuPTK6DA6I9NI=",
+	},
+}
+
+func TestHuobiSigner_Sign(t *testing.T) {
+	s := NewSigner(vectorAccessKey, vectorSecretKey)
+	s.Clock = func() time.Time { return time.Date(2017, 5, 11, 23, 19, 30, 0, time.FixedZone("CST", 8*3600)) }
+
+	for _, v := range signVectors {
+		q := v.query.Clone()
+		assert.Nil(t, s.Sign(v.method, v.url, q))
+		assert.Equal(t, v.sign, q["Signature"])
+		assert.Equal(t, "2017-05-11T15:19:30", q["Timestamp"])
+	}
+}
+
+func TestGetPayload(t *testing.T) {
+	s := NewSigner(vectorAccessKey, vectorSecretKey)
+	s.Clock = func() time.Time { return time.Date(2017, 5, 11, 15, 19, 30, 0, time.UTC) }
+
+	for _, v := range signVectors {
+		q := v.query.Clone()
+		s.Sign(v.method, v.url, q)
+		delete(q, "Signature")
+		assert.Equal(t, v.payload, getPayload(v.method, "api.huobi.pro", "/"+v.url[len("https://api.huobi.pro/"):], q))
+	}
+}
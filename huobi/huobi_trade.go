@@ -0,0 +1,393 @@
+package zb
+
+import (
+	encoding "encoding/json"
+	"errors"
+	. "github.com/berryland/x"
+	json "github.com/buger/jsonparser"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxBatchCancelSize = 50
+	openOrderPageSize  = 500
+)
+
+var orderStatuses = map[string]OrderStatus{
+	"pre-submitted":    Pending,
+	"submitting":       Pending,
+	"submitted":        Pending,
+	"partial-filled":   PartiallyFilled,
+	"partial-canceled": Cancelled,
+	"filled":           Finished,
+	"canceled":         Cancelled,
+}
+
+var orderStates = map[OrderStatus]string{
+	Pending:         "pre-submitted,submitted",
+	PartiallyFilled: "partial-filled",
+	Finished:        "filled",
+	Cancelled:       "partial-canceled,canceled",
+}
+
+type HuobiAccount struct {
+	Id    uint64
+	Type  string
+	State string
+}
+
+func (c *HuobiHttpClient) GetAccounts(accessKey, secretKey string) ([]HuobiAccount, error) {
+	bytes, err := c.getTrade("account/accounts", Query{}, accessKey, secretKey)
+	if err != nil {
+		return []HuobiAccount{}, err
+	}
+
+	var accounts []HuobiAccount
+	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+		accounts = append(accounts, HuobiAccount{Id: getUint(value, "id"), Type: getString(value, "type"), State: getString(value, "state")})
+	}, "data")
+	return accounts, nil
+}
+
+// GetAccountId returns the id of the account with accountType such as spot, ids are cached per access key.
+func (c *HuobiHttpClient) GetAccountId(accountType string, accessKey, secretKey string) (uint64, error) {
+	key := accessKey + "/" + accountType
+	c.mutex.Lock()
+	id, ok := c.accountIds[key]
+	c.mutex.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	accounts, err := c.GetAccounts(accessKey, secretKey)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.accountIds == nil {
+		c.accountIds = map[string]uint64{}
+	}
+	for _, account := range accounts {
+		c.accountIds[accessKey+"/"+account.Type] = account.Id
+	}
+	if id, ok := c.accountIds[key]; ok {
+		return id, nil
+	}
+	return 0, &ApiError{Code: UserNotFound, Message: "No " + accountType + " account"}
+}
+
+func (c *HuobiHttpClient) GetAccount(accessKey, secretKey string) (Account, error) {
+	id, err := c.GetAccountId("spot", accessKey, secretKey)
+	if err != nil {
+		return Account{}, err
+	}
+
+	bytes, err := c.getTrade("account/accounts/"+strconv.FormatUint(id, 10)+"/balance", Query{}, accessKey, secretKey)
+	if err != nil {
+		return Account{}, err
+	}
+
+	var assets []Asset
+	indexes := map[string]int{}
+	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+		currency := getString(value, "currency")
+		i, ok := indexes[currency]
+		if !ok {
+			i = len(assets)
+			indexes[currency] = i
+			assets = append(assets, Asset{Coin: Coin{Key: currency, EnName: strings.ToUpper(currency)}})
+		}
+
+		switch getString(value, "type") {
+		case "trade":
+			assets[i].Available = getFloat(value, "balance")
+		case "frozen":
+			assets[i].Freeze = getFloat(value, "balance")
+		}
+	}, "data", "list")
+
+	return Account{Assets: assets}, nil
+}
+
+func (c *HuobiHttpClient) PlaceOrder(symbol string, price, amount float64, tradeType TradeType, accessKey, secretKey string) (uint64, error) {
+	t, err := orderType(tradeType)
+	if err != nil {
+		return 0, err
+	}
+	id, err := c.GetAccountId("spot", accessKey, secretKey)
+	if err != nil {
+		return 0, err
+	}
+
+	body := map[string]string{
+		"account-id": strconv.FormatUint(id, 10),
+		"symbol":     symbol,
+		"type":       t,
+		"price":      strconv.FormatFloat(price, 'f', -1, 64),
+		"amount":     strconv.FormatFloat(amount, 'f', -1, 64),
+		"source":     "api",
+	}
+	bytes, err := c.postTrade("order/orders/place", body, accessKey, secretKey)
+	if err != nil {
+		return 0, err
+	}
+
+	return getUint(bytes, "data"), nil
+}
+
+func (c *HuobiHttpClient) PlaceOrders(symbol string, requests []OrderRequest, accessKey, secretKey string) []OrderResult {
+	results := make([]OrderResult, len(requests))
+	for i, r := range requests {
+		results[i].Id, results[i].Err = c.PlaceOrder(symbol, r.Price, r.Amount, r.TradeType, accessKey, secretKey)
+	}
+	return results
+}
+
+func (c *HuobiHttpClient) CancelOrder(symbol string, id uint64, accessKey, secretKey string) error {
+	_, err := c.postTrade("order/orders/"+strconv.FormatUint(id, 10)+"/submitcancel", map[string]string{}, accessKey, secretKey)
+	return err
+}
+
+// CancelOrders cancels ids with the batch cancel api, at most 50 orders per request.
+func (c *HuobiHttpClient) CancelOrders(symbol string, ids []uint64, accessKey, secretKey string) []OrderResult {
+	results := make([]OrderResult, len(ids))
+	for start := 0; start < len(ids); start += maxBatchCancelSize {
+		end := start + maxBatchCancelSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		orderIds := make([]string, 0, end-start)
+		for _, id := range ids[start:end] {
+			orderIds = append(orderIds, strconv.FormatUint(id, 10))
+		}
+
+		bytes, err := c.postTrade("order/orders/batchcancel", map[string][]string{"order-ids": orderIds}, accessKey, secretKey)
+		failures := map[uint64]error{}
+		if err == nil {
+			json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+				code := getString(value, "err-code")
+				failures[getUint(value, "order-id")] = &ApiError{Code: getApiCode(code), Message: getString(value, "err-msg")}
+			}, "data", "failed")
+		}
+
+		for i := start; i < end; i++ {
+			results[i].Id = ids[i]
+			if err != nil {
+				results[i].Err = err
+			} else {
+				results[i].Err = failures[ids[i]]
+			}
+		}
+	}
+	return results
+}
+
+func (c *HuobiHttpClient) GetOrder(symbol string, id uint64, accessKey, secretKey string) (Order, error) {
+	bytes, err := c.getTrade("order/orders/"+strconv.FormatUint(id, 10), Query{}, accessKey, secretKey)
+	if err != nil {
+		return Order{}, err
+	}
+
+	data, _, _, _ := json.Get(bytes, "data")
+	return parseOrder(data), nil
+}
+
+// GetOpenOrders returns every unfinished order of symbol, walking the pages of openOrderPageSize orders from the newest.
+func (c *HuobiHttpClient) GetOpenOrders(symbol string, accessKey, secretKey string) ([]Order, error) {
+	id, err := c.GetAccountId("spot", accessKey, secretKey)
+	if err != nil {
+		return []Order{}, err
+	}
+
+	var orders []Order
+	seen := map[uint64]bool{}
+	for {
+		q := Query{
+			"account-id": id,
+			"symbol":     symbol,
+			"size":       openOrderPageSize,
+		}
+		if len(orders) > 0 {
+			q["from"] = orders[len(orders)-1].Id
+			q["direct"] = "next"
+		}
+		bytes, err := c.getTrade("order/openOrders", q, accessKey, secretKey)
+		if err != nil {
+			return []Order{}, err
+		}
+
+		page := parseOrders(bytes)
+		added := 0
+		for _, order := range page {
+			if !seen[order.Id] {
+				seen[order.Id] = true
+				orders = append(orders, order)
+				added++
+			}
+		}
+		if len(page) < openOrderPageSize || added == 0 {
+			return orders, nil
+		}
+	}
+}
+
+// GetOrderHistory returns up to size orders of symbol in statuses, or in any status when statuses is empty. from is the
+// order id to continue after, zero starts with the latest order.
+func (c *HuobiHttpClient) GetOrderHistory(symbol string, statuses []OrderStatus, from uint64, size uint16, accessKey, secretKey string) ([]Order, error) {
+	if len(statuses) == 0 {
+		statuses = []OrderStatus{Pending, PartiallyFilled, Finished, Cancelled}
+	}
+	var states []string
+	for _, status := range statuses {
+		if state, ok := orderStates[status]; ok {
+			states = append(states, state)
+		}
+	}
+	if len(states) == 0 {
+		return []Order{}, errors.New("No order status to query")
+	}
+
+	q := Query{
+		"symbol": symbol,
+		"states": strings.Join(states, ","),
+		"size":   size,
+	}
+	if from > 0 {
+		q["from"] = from
+		q["direct"] = "next"
+	}
+	bytes, err := c.getTrade("order/orders", q, accessKey, secretKey)
+	if err != nil {
+		return []Order{}, err
+	}
+
+	return parseOrders(bytes), nil
+}
+
+func (c *HuobiHttpClient) GetOrderFills(id uint64, accessKey, secretKey string) ([]Fill, error) {
+	bytes, err := c.getTrade("order/orders/"+strconv.FormatUint(id, 10)+"/matchresults", Query{}, accessKey, secretKey)
+	if err != nil {
+		return []Fill{}, err
+	}
+
+	return parseFills(bytes), nil
+}
+
+func (c *HuobiHttpClient) GetFills(symbol string, size uint16, accessKey, secretKey string) ([]Fill, error) {
+	q := Query{
+		"symbol": symbol,
+		"size":   size,
+	}
+	bytes, err := c.getTrade("order/matchresults", q, accessKey, secretKey)
+	if err != nil {
+		return []Fill{}, err
+	}
+
+	return parseFills(bytes), nil
+}
+
+func (c *HuobiHttpClient) getTrade(path string, query Query, accessKey, secretKey string) ([]byte, error) {
+	if err := NewSigner(accessKey, secretKey).Sign(http.MethodGet, TradeApiUrl+path, query); err != nil {
+		return nil, err
+	}
+	return c.Client.Invoke(http.MethodGet, TradeApiUrl+path, query, nil, extractDataApiError)
+}
+
+func (c *HuobiHttpClient) postTrade(path string, body interface{}, accessKey, secretKey string) ([]byte, error) {
+	bytes, err := encoding.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	q := Query{}
+	if err := NewSigner(accessKey, secretKey).Sign(http.MethodPost, TradeApiUrl+path, q); err != nil {
+		return nil, err
+	}
+	return c.Client.Invoke(http.MethodPost, TradeApiUrl+path, q, bytes, extractDataApiError)
+}
+
+func orderType(tradeType TradeType) (string, error) {
+	switch tradeType {
+	case Buy:
+		return "buy-limit", nil
+	case Sell:
+		return "sell-limit", nil
+	default:
+		return "", errors.New("Unsupported trade type: " + strconv.Itoa(int(tradeType)))
+	}
+}
+
+func parseTradeType(orderType string) TradeType {
+	return ParseTradeType(strings.SplitN(orderType, "-", 2)[0])
+}
+
+// lookupTradeType is parseTradeType for pushed data, it reports false for an empty or unknown side instead of
+// panicking on the goroutine reading the connection.
+func lookupTradeType(orderType string) (TradeType, bool) {
+	switch strings.SplitN(orderType, "-", 2)[0] {
+	case "buy":
+		return Buy, true
+	case "sell":
+		return Sell, true
+	default:
+		return 0, false
+	}
+}
+
+func parseOrders(value []byte) []Order {
+	var orders []Order
+	json.ArrayEach(value, func(value []byte, dataType json.ValueType, offset int, err error) {
+		orders = append(orders, parseOrder(value))
+	}, "data")
+	return orders
+}
+
+func parseOrder(value []byte) Order {
+	tradeAmount := getFloat(value, "field-amount")
+	if tradeAmount == 0 {
+		tradeAmount = getFloat(value, "filled-amount")
+	}
+	tradeMoney := getFloat(value, "field-cash-amount")
+	if tradeMoney == 0 {
+		tradeMoney = getFloat(value, "filled-cash-amount")
+	}
+	var average float64
+	if tradeAmount > 0 {
+		average = tradeMoney / tradeAmount
+	}
+
+	return Order{
+		Id:          getUint(value, "id"),
+		Price:       getFloat(value, "price"),
+		Average:     average,
+		TotalAmount: getFloat(value, "amount"),
+		TradeAmount: tradeAmount,
+		TradeMoney:  tradeMoney,
+		Symbol:      getString(value, "symbol"),
+		Status:      orderStatuses[getString(value, "state")],
+		TradeType:   parseTradeType(getString(value, "type")),
+		Time:        getUint(value, "created-at"),
+	}
+}
+
+func parseFills(value []byte) []Fill {
+	var fills []Fill
+	json.ArrayEach(value, func(value []byte, dataType json.ValueType, offset int, err error) {
+		fills = append(fills, Fill{
+			Id:        getUint(value, "id"),
+			OrderId:   getUint(value, "order-id"),
+			Symbol:    getString(value, "symbol"),
+			TradeType: parseTradeType(getString(value, "type")),
+			Price:     getFloat(value, "price"),
+			Amount:    getFloat(value, "filled-amount"),
+			Fee:       getFloat(value, "filled-fees"),
+			Time:      getUint(value, "created-at"),
+		})
+	}, "data")
+	return fills
+}
@@ -0,0 +1,234 @@
+package zb
+
+import (
+	. "github.com/berryland/x"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	testAccessKey = "access"
+	testSecretKey = "secret"
+)
+
+func assertSigned(t *testing.T, r *http.Request) {
+	q := r.URL.Query()
+	assert.Equal(t, testAccessKey, q.Get("AccessKeyId"))
+	assert.Equal(t, "HmacSHA256", q.Get("SignatureMethod"))
+	assert.Equal(t, "2", q.Get("SignatureVersion"))
+	assert.NotEmpty(t, q.Get("Timestamp"))
+	assert.NotEmpty(t, q.Get("Signature"))
+}
+
+func tradeHandler(t *testing.T, routes map[string]func(w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assertSigned(t, r)
+		if r.URL.Path == "/v1/account/accounts" {
+			w.Write([]byte(`{"status":"ok","data":[{"id":100009,"type":"spot","state":"working","user-id":1000},{"id":100010,"type":"margin","state":"working","user-id":1000}]}`))
+			return
+		}
+		if route, ok := routes[r.Method+" "+r.URL.Path]; ok {
+			route(w, r)
+			return
+		}
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}
+}
+
+func TestHuobiHttpClient_GetAccountId(t *testing.T) {
+	var hits int32
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		tradeHandler(t, nil)(w, r)
+	})
+	defer shutdown()
+
+	id, err := c.GetAccountId("spot", testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(100009), id)
+	id, err = c.GetAccountId("margin", testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(100010), id)
+	assert.Equal(t, int32(1), hits)
+
+	_, err = c.GetAccountId("otc", testAccessKey, testSecretKey)
+	assert.Equal(t, UserNotFound, err.(*ApiError).Code)
+}
+
+func TestHuobiHttpClient_GetAccount(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /v1/account/accounts/100009/balance": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"status":"ok","data":{"id":100009,"type":"spot","state":"working","list":[{"currency":"usdt","type":"trade","balance":"500.009195"},{"currency":"usdt","type":"frozen","balance":"328.048"},{"currency":"btc","type":"trade","balance":"1.5"}]}}`))
+		},
+	}))
+	defer shutdown()
+
+	account, err := c.GetAccount(testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []Asset{
+		{Available: 500.009195, Freeze: 328.048, Coin: Coin{Key: "usdt", EnName: "USDT"}},
+		{Available: 1.5, Coin: Coin{Key: "btc", EnName: "BTC"}},
+	}, account.Assets)
+}
+
+func TestHuobiHttpClient_PlaceOrder(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"POST /v1/order/orders/place": func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.JSONEq(t, `{"account-id":"100009","amount":"0.01","price":"11000.5","source":"api","symbol":"btcusdt","type":"sell-limit"}`, string(body))
+			w.Write([]byte(`{"status":"ok","data":"59378"}`))
+		},
+	}))
+	defer shutdown()
+
+	id, err := c.PlaceOrder("btcusdt", 11000.5, 0.01, Sell, testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(59378), id)
+
+	_, err = c.PlaceOrder("btcusdt", 11000.5, 0.01, All, testAccessKey, testSecretKey)
+	assert.EqualError(t, err, "Unsupported trade type: -1")
+}
+
+func TestHuobiHttpClient_CancelOrder(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"POST /v1/order/orders/59378/submitcancel": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"status":"error","err-code":"order-orderstate-error","err-msg":"order state error"}`))
+		},
+	}))
+	defer shutdown()
+
+	err := c.CancelOrder("btcusdt", 59378, testAccessKey, testSecretKey)
+	assert.Equal(t, &ApiError{Code: InvalidOrderState, Message: "order state error"}, err)
+}
+
+func TestHuobiHttpClient_CancelOrders(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"POST /v1/order/orders/batchcancel": func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.JSONEq(t, `{"order-ids":["1","2","3"]}`, string(body))
+			w.Write([]byte(`{"status":"ok","data":{"success":["1","3"],"failed":[{"err-msg":"invalid argument","order-id":"2","err-code":"bad-argument"}]}}`))
+		},
+	}))
+	defer shutdown()
+
+	results := c.CancelOrders("btcusdt", []uint64{1, 2, 3}, testAccessKey, testSecretKey)
+	assert.Equal(t, []OrderResult{{Id: 1}, {Id: 2, Err: &ApiError{Code: InvalidArgument, Message: "invalid argument"}}, {Id: 3}}, results)
+}
+
+func TestHuobiHttpClient_GetOrder(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /v1/order/orders/59378": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"status":"ok","data":{"id":59378,"symbol":"btcusdt","account-id":100009,"amount":"0.5000000000","price":"11000.0000000000","created-at":1494901162595,"type":"buy-limit","field-amount":"0.2000000000","field-cash-amount":"2190.0000000000","field-fees":"0.0004","finished-at":0,"source":"api","state":"partial-filled","canceled-at":0}}`))
+		},
+	}))
+	defer shutdown()
+
+	order, err := c.GetOrder("btcusdt", 59378, testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, Order{Id: 59378, Price: 11000, Average: 10950, TotalAmount: 0.5, TradeAmount: 0.2, TradeMoney: 2190, Symbol: "btcusdt", Status: PartiallyFilled, TradeType: Buy, Time: 1494901162595}, order)
+}
+
+func TestHuobiHttpClient_GetOpenOrders(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /v1/order/openOrders": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "100009", r.URL.Query().Get("account-id"))
+			assert.Equal(t, "btcusdt", r.URL.Query().Get("symbol"))
+			w.Write([]byte(`{"status":"ok","data":[{"id":1,"symbol":"btcusdt","amount":"1","price":"10000","created-at":1,"type":"sell-limit","filled-amount":"0","filled-cash-amount":"0","state":"submitted"}]}`))
+		},
+	}))
+	defer shutdown()
+
+	orders, err := c.GetOpenOrders("btcusdt", testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []Order{{Id: 1, Price: 10000, TotalAmount: 1, Symbol: "btcusdt", Status: Pending, TradeType: Sell, Time: 1}}, orders)
+}
+
+func TestHuobiHttpClient_GetOpenOrdersPages(t *testing.T) {
+	var froms []string
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /v1/order/openOrders": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			froms = append(froms, q.Get("from"))
+			first, count := 1000, openOrderPageSize
+			if q.Get("from") != "" {
+				assert.Equal(t, "next", q.Get("direct"))
+				first, count = 500, 2
+			}
+			var data []string
+			for i := 0; i < count; i++ {
+				data = append(data, `{"id":`+strconv.Itoa(first-i)+`,"symbol":"btcusdt","type":"buy-limit","state":"submitted"}`)
+			}
+			w.Write([]byte(`{"status":"ok","data":[` + strings.Join(data, ",") + `]}`))
+		},
+	}))
+	defer shutdown()
+
+	orders, err := c.GetOpenOrders("btcusdt", testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, openOrderPageSize+2, len(orders))
+	assert.Equal(t, uint64(499), orders[len(orders)-1].Id)
+	assert.Equal(t, []string{"", "501"}, froms)
+}
+
+func TestHuobiHttpClient_GetOrderHistory(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /v1/order/orders": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			assert.Equal(t, "filled,partial-canceled,canceled", q.Get("states"))
+			assert.Equal(t, "100", q.Get("from"))
+			assert.Equal(t, "next", q.Get("direct"))
+			w.Write([]byte(`{"status":"ok","data":[{"id":99,"symbol":"btcusdt","amount":"1","price":"10000","type":"buy-limit","field-amount":"1","field-cash-amount":"10000","state":"filled"}]}`))
+		},
+	}))
+	defer shutdown()
+
+	orders, err := c.GetOrderHistory("btcusdt", []OrderStatus{Finished, Cancelled}, 100, 10, testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(orders))
+	assert.Equal(t, Finished, orders[0].Status)
+	assert.Equal(t, 10000.0, orders[0].Average)
+}
+
+func TestHuobiHttpClient_GetOrderHistoryAnyStatus(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /v1/order/orders": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "pre-submitted,submitted,partial-filled,filled,partial-canceled,canceled", r.URL.Query().Get("states"))
+			w.Write([]byte(`{"status":"ok","data":[]}`))
+		},
+	}))
+	defer shutdown()
+
+	_, err := c.GetOrderHistory("btcusdt", nil, 0, 10, testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+}
+
+func TestHuobiHttpClient_GetOrderFills(t *testing.T) {
+	c, shutdown := newTestHttpClient(tradeHandler(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /v1/order/orders/59378/matchresults": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"status":"ok","data":[{"id":29553,"order-id":59378,"match-id":59335,"symbol":"btcusdt","type":"buy-limit","source":"api","price":"10950.0000000000","filled-amount":"0.2000000000","filled-fees":"0.0004000000","created-at":1494901400435}]}`))
+		},
+	}))
+	defer shutdown()
+
+	fills, err := c.GetOrderFills(59378, testAccessKey, testSecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []Fill{{Id: 29553, OrderId: 59378, Symbol: "btcusdt", TradeType: Buy, Price: 10950, Amount: 0.2, Fee: 0.0004, Time: 1494901400435}}, fills)
+}
+
+func TestLookupTradeType(t *testing.T) {
+	for orderType, expected := range map[string]TradeType{"buy-limit": Buy, "sell-market": Sell, "buy": Buy} {
+		tradeType, ok := lookupTradeType(orderType)
+		assert.True(t, ok)
+		assert.Equal(t, expected, tradeType)
+	}
+	for _, orderType := range []string{"", "swap-limit"} {
+		_, ok := lookupTradeType(orderType)
+		assert.False(t, ok)
+	}
+}
@@ -0,0 +1,516 @@
+package zb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	encoding "encoding/json"
+	. "github.com/berryland/x"
+	json "github.com/buger/jsonparser"
+	"github.com/gorilla/websocket"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	WebSocketServerUrl  = "wss://api.huobi.pro/ws"
+	maxKlineRequestSize = 300
+)
+
+// HuobiWebSocketClient keeps its subscriptions across Disconnect and dropped connections, Connect sends them again.
+// Requests waiting for a response when the connection drops fail with ErrNotConnected. Listeners are called from a
+// dispatcher rather than the goroutine reading the connection, so they may send requests and wait for them.
+type HuobiWebSocketClient struct {
+	Url        string
+	Timeout    time.Duration
+	Hooks      []WsHook
+	mutex      sync.Mutex
+	write      sync.Mutex
+	conn       *websocket.Conn
+	decoders   map[string]func([]byte) interface{}
+	callbacks  map[string][]listener
+	topics     map[string]*sync.Mutex
+	listenerId uint64
+	pending    map[string]chan []byte
+	id         uint64
+}
+
+var _ WsApiClient = (*HuobiWebSocketClient)(nil)
+var _ KlineRangeClient = (*HuobiWebSocketClient)(nil)
+
+func NewWebSocketClient() *HuobiWebSocketClient {
+	return &HuobiWebSocketClient{
+		Url:       WebSocketServerUrl,
+		Timeout:   10 * time.Second,
+		decoders:  make(map[string]func([]byte) interface{}),
+		callbacks: make(map[string][]listener),
+		topics:    make(map[string]*sync.Mutex),
+		pending:   make(map[string]chan []byte),
+	}
+}
+
+type subMessage struct {
+	Sub string `json:"sub"`
+	Id  string `json:"id"`
+}
+
+type unsubMessage struct {
+	Unsub string `json:"unsub"`
+	Id    string `json:"id"`
+}
+
+type reqMessage struct {
+	Req  string `json:"req"`
+	Id   string `json:"id"`
+	From uint64 `json:"from,omitempty"`
+	To   uint64 `json:"to,omitempty"`
+}
+
+type pongMessage struct {
+	Pong int64 `json:"pong"`
+}
+
+// Connect dials the server and sends the sub of every topic subscribed before.
+func (c *HuobiWebSocketClient) Connect(ctx context.Context) error {
+	c.mutex.Lock()
+	if c.conn != nil {
+		c.mutex.Unlock()
+		return nil
+	}
+
+	conn, err := DialWebSocket(ctx, c.Url)
+	if err != nil {
+		c.mutex.Unlock()
+		return err
+	}
+	c.conn = conn
+	c.mutex.Unlock()
+
+	go c.read(conn, NewDispatcher())
+	c.resubscribe()
+	return nil
+}
+
+func (c *HuobiWebSocketClient) Disconnect() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.conn == nil {
+		return
+	}
+
+	c.conn.Close()
+	c.conn = nil
+	c.failPending()
+}
+
+// resubscribe sends the sub of every registered topic without waiting for the acks.
+func (c *HuobiWebSocketClient) resubscribe() {
+	c.mutex.Lock()
+	var topics []string
+	for topic := range c.decoders {
+		topics = append(topics, topic)
+	}
+	c.mutex.Unlock()
+
+	for _, topic := range topics {
+		lock := c.topicLock(topic)
+		lock.Lock()
+		c.mutex.Lock()
+		_, subscribed := c.decoders[topic]
+		c.mutex.Unlock()
+		if subscribed {
+			c.send(topic, subMessage{Sub: topic, Id: c.nextId()})
+		}
+		lock.Unlock()
+	}
+}
+
+func (c *HuobiWebSocketClient) read(conn *websocket.Conn, dispatcher *Dispatcher) {
+	defer func() {
+		dispatcher.Close()
+		c.mutex.Lock()
+		if c.conn == conn {
+			c.conn.Close()
+			c.conn = nil
+			c.failPending()
+		}
+		c.mutex.Unlock()
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		bytes, err := gunzip(data)
+		if err != nil {
+			continue
+		}
+		c.dispatch(bytes, dispatcher)
+	}
+}
+
+func (c *HuobiWebSocketClient) dispatch(bytes []byte, dispatcher *Dispatcher) {
+	if ping, err := json.GetInt(bytes, "ping"); err == nil {
+		c.notify(&Frame{Payload: bytes, Time: time.Now()})
+		c.send("", pongMessage{Pong: ping})
+		return
+	}
+
+	if topic, err := json.GetString(bytes, "ch"); err == nil {
+		c.notify(&Frame{Channel: topic, Payload: bytes, Time: time.Now()})
+		c.mutex.Lock()
+		decoder, ok := c.decoders[topic]
+		listeners := c.callbacks[topic]
+		c.mutex.Unlock()
+		if !ok {
+			return
+		}
+
+		value := decoder(bytes)
+		dispatcher.Add(func() {
+			for _, l := range listeners {
+				l.callback(value)
+			}
+		})
+		return
+	}
+
+	topic := getString(bytes, "rep")
+	for _, key := range []string{"subbed", "unsubbed"} {
+		if topic == "" {
+			topic = getString(bytes, key)
+		}
+	}
+	c.notify(&Frame{Channel: topic, Payload: bytes, Time: time.Now()})
+
+	id := getString(bytes, "id")
+	c.mutex.Lock()
+	response, ok := c.pending[id]
+	delete(c.pending, id)
+	c.mutex.Unlock()
+	if ok {
+		response <- bytes
+	}
+}
+
+func (c *HuobiWebSocketClient) SubscribeTicker(symbol string, callback func(ticker Ticker)) (Subscription, error) {
+	return c.SubscribeDetail(symbol, callback)
+}
+
+// SubscribeDetail streams the 24 hours statistics of symbol, the ticker carries no bid or ask.
+func (c *HuobiWebSocketClient) SubscribeDetail(symbol string, callback func(ticker Ticker)) (Subscription, error) {
+	return c.subscribe("market."+symbol+".detail", func(value []byte) interface{} {
+		tick, _, _, _ := json.Get(value, "tick")
+		return marshalDetail(tick, getUint(value, "ts"))
+	}, func(v interface{}) {
+		callback(v.(Ticker))
+	})
+}
+
+func (c *HuobiWebSocketClient) SubscribeKline(symbol string, period string, callback func(kline Kline)) (Subscription, error) {
+	return c.subscribe("market."+symbol+".kline."+period, func(value []byte) interface{} {
+		tick, _, _, _ := json.Get(value, "tick")
+		return marshalKline(tick)
+	}, func(v interface{}) {
+		callback(v.(Kline))
+	})
+}
+
+func (c *HuobiWebSocketClient) SubscribeDepth(symbol string, step DepthStep, callback func(depth Depth)) (Subscription, error) {
+	return c.subscribe("market."+symbol+".depth."+string(step), func(value []byte) interface{} {
+		asks, bids := ParseDepthEntries(value, "tick", "asks"), ParseDepthEntries(value, "tick", "bids")
+		return Depth{Asks: asks, Bids: bids, Time: getUint(value, "ts")}
+	}, func(v interface{}) {
+		callback(v.(Depth))
+	})
+}
+
+func (c *HuobiWebSocketClient) SubscribeTrades(symbol string, callback func(trades []Trade)) (Subscription, error) {
+	return c.subscribe("market."+symbol+".trade.detail", func(value []byte) interface{} {
+		return marshalTrades(value, "tick", "data")
+	}, func(v interface{}) {
+		callback(v.([]Trade))
+	})
+}
+
+// Subscribe adds callback as a listener of topic. The first listener of a topic sends the sub and waits for it to be
+// acknowledged, its decoder is shared by the listeners added later.
+func (c *HuobiWebSocketClient) Subscribe(topic string, decoder func([]byte) interface{}, callback func(interface{})) error {
+	_, err := c.subscribe(topic, decoder, callback)
+	return err
+}
+
+type listener struct {
+	id       uint64
+	callback func(interface{})
+}
+
+type topicSubscription struct {
+	client *HuobiWebSocketClient
+	topic  string
+	id     uint64
+}
+
+// Unsubscribe removes the listener, the unsub of the topic is sent once its last listener is gone.
+func (s *topicSubscription) Unsubscribe() error {
+	lock := s.client.topicLock(s.topic)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !s.client.unregisterListener(s.topic, s.id) {
+		return nil
+	}
+	id := s.client.nextId()
+	_, err := s.client.call(id, s.topic, unsubMessage{Unsub: s.topic, Id: id})
+	if err == ErrNotConnected {
+		return nil
+	}
+	return err
+}
+
+func (c *HuobiWebSocketClient) subscribe(topic string, decoder func([]byte) interface{}, callback func(interface{})) (Subscription, error) {
+	lock := c.topicLock(topic)
+	lock.Lock()
+	defer lock.Unlock()
+
+	listenerId, first := c.register(topic, decoder, callback)
+	if first {
+		id := c.nextId()
+		if _, err := c.call(id, topic, subMessage{Sub: topic, Id: id}); err != nil {
+			c.unregister(topic)
+			return nil, err
+		}
+	}
+	return &topicSubscription{client: c, topic: topic, id: listenerId}, nil
+}
+
+// Unsubscribe removes every listener of topic.
+func (c *HuobiWebSocketClient) Unsubscribe(topic string) error {
+	lock := c.topicLock(topic)
+	lock.Lock()
+	defer lock.Unlock()
+
+	id := c.nextId()
+	_, err := c.call(id, topic, unsubMessage{Unsub: topic, Id: id})
+	c.unregister(topic)
+	return err
+}
+
+// RequestKlines queries the klines of symbol opened between from and to, both in seconds.
+func (c *HuobiWebSocketClient) RequestKlines(symbol string, period string, from, to uint64) ([]Kline, error) {
+	bytes, err := c.Request("market."+symbol+".kline."+period, from, to)
+	if err != nil {
+		return []Kline{}, err
+	}
+
+	var klines []Kline
+	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+		klines = append(klines, marshalKline(value))
+	}, "data")
+	return klines, nil
+}
+
+// GetKlineRange requests the klines of pair opened in [from, to), both in milliseconds, in reqs of at most
+// maxKlineRequestSize klines.
+func (c *HuobiWebSocketClient) GetKlineRange(pair Pair, period string, from, to uint64) ([]Kline, error) {
+	d, err := ParsePeriod(period)
+	if err != nil {
+		return []Kline{}, err
+	}
+
+	window := uint64(d/time.Millisecond) * maxKlineRequestSize
+	var klines []Kline
+	for cursor := from; cursor < to; cursor += window {
+		next := cursor + window
+		if next > to {
+			next = to
+		}
+		page, err := c.RequestKlines(parseSymbol(pair), period, cursor/1000, (next-1)/1000)
+		if err != nil {
+			return []Kline{}, err
+		}
+		for _, kline := range page {
+			if kline.Time >= cursor && kline.Time < next {
+				klines = append(klines, kline)
+			}
+		}
+	}
+
+	sort.Slice(klines, func(i, j int) bool {
+		return klines[i].Time < klines[j].Time
+	})
+	return klines, nil
+}
+
+func (c *HuobiWebSocketClient) RequestDepth(symbol string, step DepthStep) (Depth, error) {
+	bytes, err := c.Request("market."+symbol+".depth."+string(step), 0, 0)
+	if err != nil {
+		return Depth{}, err
+	}
+
+	asks, bids := ParseDepthEntries(bytes, "data", "asks"), ParseDepthEntries(bytes, "data", "bids")
+	return Depth{Asks: asks, Bids: bids, Time: getUint(bytes, "ts")}, nil
+}
+
+func (c *HuobiWebSocketClient) RequestTrades(symbol string) ([]Trade, error) {
+	bytes, err := c.Request("market."+symbol+".trade.detail", 0, 0)
+	if err != nil {
+		return []Trade{}, err
+	}
+
+	return marshalTrades(bytes, "data"), nil
+}
+
+func (c *HuobiWebSocketClient) RequestDetail(symbol string) (Ticker, error) {
+	bytes, err := c.Request("market."+symbol+".detail", 0, 0)
+	if err != nil {
+		return Ticker{}, err
+	}
+
+	data, _, _, _ := json.Get(bytes, "data")
+	return marshalDetail(data, getUint(bytes, "ts")), nil
+}
+
+// Request sends a req for topic and returns the whole response, from and to are in seconds and ignored when zero.
+func (c *HuobiWebSocketClient) Request(topic string, from, to uint64) ([]byte, error) {
+	id := c.nextId()
+	return c.call(id, topic, reqMessage{Req: topic, Id: id, From: from, To: to})
+}
+
+func (c *HuobiWebSocketClient) call(id string, topic string, message interface{}) ([]byte, error) {
+	response := make(chan []byte, 1)
+	c.mutex.Lock()
+	c.pending[id] = response
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		delete(c.pending, id)
+		c.mutex.Unlock()
+	}()
+
+	if err := c.send(topic, message); err != nil {
+		return nil, err
+	}
+
+	select {
+	case bytes, ok := <-response:
+		if !ok {
+			return nil, ErrNotConnected
+		}
+		if err := extractDataApiError(bytes); err != nil {
+			return nil, err
+		}
+		return bytes, nil
+	case <-time.After(c.Timeout):
+		return nil, ErrTimeout
+	}
+}
+
+func (c *HuobiWebSocketClient) send(topic string, message interface{}) error {
+	bytes, err := encoding.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	conn := c.conn
+	c.mutex.Unlock()
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	c.notify(&Frame{Outgoing: true, Channel: topic, Payload: bytes, Time: time.Now()})
+	c.write.Lock()
+	defer c.write.Unlock()
+	return conn.WriteMessage(websocket.TextMessage, bytes)
+}
+
+func (c *HuobiWebSocketClient) notify(frame *Frame) {
+	for _, hook := range c.Hooks {
+		hook(frame)
+	}
+}
+
+// register adds callback as a listener of topic and reports whether it is the first one.
+func (c *HuobiWebSocketClient) register(topic string, decoder func(value []byte) interface{}, callback func(interface{})) (uint64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, subscribed := c.decoders[topic]
+	if !subscribed {
+		c.decoders[topic] = decoder
+	}
+	c.listenerId++
+	c.callbacks[topic] = append(c.callbacks[topic], listener{id: c.listenerId, callback: callback})
+	return c.listenerId, !subscribed
+}
+
+func (c *HuobiWebSocketClient) unregister(topic string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.decoders, topic)
+	delete(c.callbacks, topic)
+}
+
+// unregisterListener removes the listener id of topic and reports whether it was the last one.
+func (c *HuobiWebSocketClient) unregisterListener(topic string, id uint64) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	listeners := c.callbacks[topic]
+	for i, l := range listeners {
+		if l.id != id {
+			continue
+		}
+
+		// copy so that a reader holding the old slice is not affected
+		remaining := append(append([]listener(nil), listeners[:i]...), listeners[i+1:]...)
+		if len(remaining) > 0 {
+			c.callbacks[topic] = remaining
+			return false
+		}
+		delete(c.callbacks, topic)
+		delete(c.decoders, topic)
+		return true
+	}
+	return false
+}
+
+// topicLock returns the lock serializing the sub and unsub of topic with the listener changes they follow.
+func (c *HuobiWebSocketClient) topicLock(topic string) *sync.Mutex {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	lock, ok := c.topics[topic]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.topics[topic] = lock
+	}
+	return lock
+}
+
+// failPending makes every waiting request fail with ErrNotConnected, the caller must hold the mutex.
+func (c *HuobiWebSocketClient) failPending() {
+	for id, response := range c.pending {
+		delete(c.pending, id)
+		close(response)
+	}
+}
+
+func (c *HuobiWebSocketClient) nextId() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.id++
+	return strconv.FormatUint(c.id, 10)
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
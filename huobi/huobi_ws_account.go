@@ -0,0 +1,478 @@
+package zb
+
+import (
+	"context"
+	encoding "encoding/json"
+	. "github.com/berryland/x"
+	json "github.com/buger/jsonparser"
+	"github.com/gorilla/websocket"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const AccountWebSocketServerUrl = "wss://api.huobi.pro/ws/v1"
+
+// WsApiCodes maps the numeric err-code of the account websocket onto ApiCode.
+var WsApiCodes = map[int64]ApiCode{
+	2000: InvalidIpAddress,
+	2001: UserNotFound,
+	2002: AuthenticationFailed,
+	2003: AuthenticationFailed,
+	2020: InvalidArgument,
+	2021: InvalidArgument,
+	2030: TooFrequent,
+	2040: InvalidArgument,
+	4000: TooFrequent,
+}
+
+// HuobiAccountWebSocketClient streams the order and balance changes of one api key. It reconnects with Backoff whenever
+// the connection drops after Connect, until Disconnect is called. Every new connection is authenticated and subscribed
+// to every topic again, then OnGap is called for each topic as notifications may have been missed in between.
+// Requests waiting for a response when the connection drops fail with ErrNotConnected.
+//
+// The exported fields are configuration and must be set before Connect.
+type HuobiAccountWebSocketClient struct {
+	Url           string
+	Timeout       time.Duration
+	Hooks         []WsHook
+	Signer        Signer
+	Backoff       *Backoff
+	OnStateChange func(state ConnState)
+	OnGap         func(topic string)
+	running       bool
+	done          chan struct{}
+	mutex         sync.Mutex
+	write         sync.Mutex
+	conn          *websocket.Conn
+	callbacks     map[string]func([]byte)
+	pending       map[string]chan []byte
+	balances      map[string]Asset
+	cid           uint64
+}
+
+func NewAccountWebSocketClient(accessKey, secretKey string) *HuobiAccountWebSocketClient {
+	return &HuobiAccountWebSocketClient{
+		Url:       AccountWebSocketServerUrl,
+		Timeout:   10 * time.Second,
+		Signer:    NewSigner(accessKey, secretKey),
+		Backoff:   NewBackoff(time.Second, time.Minute),
+		callbacks: make(map[string]func([]byte)),
+		pending:   make(map[string]chan []byte),
+		balances:  make(map[string]Asset),
+	}
+}
+
+type opMessage struct {
+	Op    string `json:"op"`
+	Cid   string `json:"cid,omitempty"`
+	Topic string `json:"topic,omitempty"`
+	Ts    uint64 `json:"ts,omitempty"`
+}
+
+// Connect dials the server, authenticates and subscribes every topic subscribed before. ctx only bounds the first
+// dial, later reconnects are bounded by Timeout.
+func (c *HuobiAccountWebSocketClient) Connect(ctx context.Context) error {
+	c.mutex.Lock()
+	if c.running {
+		c.mutex.Unlock()
+		return nil
+	}
+	c.running = true
+	c.done = make(chan struct{})
+	c.mutex.Unlock()
+
+	c.setState(Connecting)
+	conn, err := c.dial(ctx)
+	if err != nil {
+		c.Disconnect()
+		return err
+	}
+	closed := c.start(conn)
+	if err := c.setup(false); err != nil {
+		c.Disconnect()
+		return err
+	}
+	c.setState(Connected)
+
+	go c.run(conn, closed)
+	return nil
+}
+
+func (c *HuobiAccountWebSocketClient) Disconnect() {
+	c.mutex.Lock()
+	if !c.running {
+		c.mutex.Unlock()
+		return
+	}
+	c.running = false
+	close(c.done)
+
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	c.failPending()
+	c.mutex.Unlock()
+	c.setState(Disconnected)
+}
+
+func (c *HuobiAccountWebSocketClient) dial(ctx context.Context) (*websocket.Conn, error) {
+	conn, err := DialWebSocket(ctx, c.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.running {
+		conn.Close()
+		return nil, ErrNotConnected
+	}
+	c.conn = conn
+	return conn, nil
+}
+
+// start reads conn in a new goroutine, the returned channel is closed once the connection is gone.
+func (c *HuobiAccountWebSocketClient) start(conn *websocket.Conn) <-chan struct{} {
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		c.read(conn)
+	}()
+	return closed
+}
+
+func (c *HuobiAccountWebSocketClient) run(conn *websocket.Conn, closed <-chan struct{}) {
+	for conn != nil {
+		<-closed
+		conn, closed = c.reconnect(conn)
+	}
+}
+
+// reconnect dials until a connection is authenticated and subscribed again. It returns nil when the client was
+// disconnected.
+func (c *HuobiAccountWebSocketClient) reconnect(old *websocket.Conn) (*websocket.Conn, <-chan struct{}) {
+	c.mutex.Lock()
+	if !c.running || c.conn != old {
+		c.mutex.Unlock()
+		return nil, nil
+	}
+	c.conn = nil
+	done := c.done
+	c.failPending()
+	c.mutex.Unlock()
+	old.Close()
+	c.setState(Disconnected)
+
+	for attempt := 0; ; attempt++ {
+		c.setState(Connecting)
+		ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+		conn, err := c.dial(ctx)
+		cancel()
+		if err == nil {
+			closed := c.start(conn)
+			if err = c.setup(true); err == nil {
+				c.setState(Connected)
+				return conn, closed
+			}
+			c.drop(conn)
+			<-closed
+		}
+
+		c.setState(Disconnected)
+		select {
+		case <-done:
+			return nil, nil
+		case <-time.After(c.Backoff.Duration(attempt)):
+		}
+	}
+}
+
+// setup authenticates the connection and subscribes every topic, gap tells whether notifications may have been missed
+// since the last connection.
+func (c *HuobiAccountWebSocketClient) setup(gap bool) error {
+	if err := c.auth(); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	var topics []string
+	for topic := range c.callbacks {
+		topics = append(topics, topic)
+	}
+	c.mutex.Unlock()
+
+	for _, topic := range topics {
+		if _, err := c.call(opMessage{Op: "sub", Topic: topic}); err != nil {
+			return err
+		}
+		if gap && c.OnGap != nil {
+			c.OnGap(topic)
+		}
+	}
+	return nil
+}
+
+// drop closes conn and forgets it unless it was already replaced.
+func (c *HuobiAccountWebSocketClient) drop(conn *websocket.Conn) {
+	c.mutex.Lock()
+	if c.conn == conn {
+		c.conn = nil
+		c.failPending()
+	}
+	c.mutex.Unlock()
+	conn.Close()
+}
+
+func (c *HuobiAccountWebSocketClient) setState(state ConnState) {
+	if c.OnStateChange != nil {
+		c.OnStateChange(state)
+	}
+}
+
+func (c *HuobiAccountWebSocketClient) auth() error {
+	q := Query{}
+	if err := c.Signer.Sign("GET", c.Url, q); err != nil {
+		return err
+	}
+
+	message := map[string]interface{}{"op": "auth"}
+	for k, v := range q {
+		message[k] = v
+	}
+	_, err := c.call(message)
+	return err
+}
+
+func (c *HuobiAccountWebSocketClient) read(conn *websocket.Conn) {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		bytes, err := gunzip(data)
+		if err != nil {
+			continue
+		}
+		c.dispatch(bytes)
+	}
+}
+
+func (c *HuobiAccountWebSocketClient) dispatch(bytes []byte) {
+	topic := getString(bytes, "topic")
+	c.notify(&Frame{Channel: topic, Payload: bytes, Time: time.Now()})
+
+	switch getString(bytes, "op") {
+	case "ping":
+		c.send(opMessage{Op: "pong", Ts: getUint(bytes, "ts")})
+	case "notify":
+		c.mutex.Lock()
+		callback, ok := c.callbacks[topic]
+		c.mutex.Unlock()
+		if ok {
+			callback(bytes)
+		}
+	default:
+		cid := getString(bytes, "cid")
+		c.mutex.Lock()
+		response, ok := c.pending[cid]
+		delete(c.pending, cid)
+		c.mutex.Unlock()
+		if ok {
+			response <- bytes
+		}
+	}
+}
+
+// SubscribeOrders streams the changes of the orders of symbol. A notification only carries the latest match, so the
+// orders have no TradeMoney and Average. Notifications without a known order type are skipped.
+func (c *HuobiAccountWebSocketClient) SubscribeOrders(symbol string, callback func(order Order)) error {
+	return c.Subscribe("orders."+symbol, func(value []byte) {
+		if order, ok := parseOrderNotification(value); ok {
+			callback(order)
+		}
+	})
+}
+
+// SubscribeAccounts streams the balance changes of the account together with the event that caused them, such as
+// order.place or deposit. Assets hold the latest known balances, a side never notified before is reported as zero.
+func (c *HuobiAccountWebSocketClient) SubscribeAccounts(callback func(event string, assets []Asset)) error {
+	return c.Subscribe("accounts", func(value []byte) {
+		callback(getString(value, "data", "event"), c.updateBalances(value))
+	})
+}
+
+func (c *HuobiAccountWebSocketClient) Subscribe(topic string, callback func([]byte)) error {
+	c.mutex.Lock()
+	c.callbacks[topic] = callback
+	c.mutex.Unlock()
+
+	if _, err := c.call(opMessage{Op: "sub", Topic: topic}); err != nil {
+		c.unregister(topic)
+		return err
+	}
+	return nil
+}
+
+func (c *HuobiAccountWebSocketClient) Unsubscribe(topic string) error {
+	_, err := c.call(opMessage{Op: "unsub", Topic: topic})
+	c.unregister(topic)
+	return err
+}
+
+func (c *HuobiAccountWebSocketClient) updateBalances(value []byte) []Asset {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var currencies []string
+	json.ArrayEach(value, func(value []byte, dataType json.ValueType, offset int, err error) {
+		currency := getString(value, "currency")
+		asset, ok := c.balances[currency]
+		if !ok {
+			asset.Coin = Coin{Key: currency, EnName: strings.ToUpper(currency)}
+		}
+
+		switch getString(value, "type") {
+		case "trade":
+			asset.Available = getFloat(value, "balance")
+		case "frozen":
+			asset.Freeze = getFloat(value, "balance")
+		}
+		c.balances[currency] = asset
+		currencies = append(currencies, currency)
+	}, "data", "list")
+
+	var assets []Asset
+	seen := map[string]bool{}
+	for _, currency := range currencies {
+		if !seen[currency] {
+			seen[currency] = true
+			assets = append(assets, c.balances[currency])
+		}
+	}
+	return assets
+}
+
+func (c *HuobiAccountWebSocketClient) call(message interface{}) ([]byte, error) {
+	cid := c.nextCid()
+	switch m := message.(type) {
+	case opMessage:
+		m.Cid = cid
+		message = m
+	case map[string]interface{}:
+		m["cid"] = cid
+	}
+
+	response := make(chan []byte, 1)
+	c.mutex.Lock()
+	c.pending[cid] = response
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		delete(c.pending, cid)
+		c.mutex.Unlock()
+	}()
+
+	if err := c.send(message); err != nil {
+		return nil, err
+	}
+
+	select {
+	case bytes, ok := <-response:
+		if !ok {
+			return nil, ErrNotConnected
+		}
+		if err := extractWsApiError(bytes); err != nil {
+			return nil, err
+		}
+		return bytes, nil
+	case <-time.After(c.Timeout):
+		return nil, ErrTimeout
+	}
+}
+
+func (c *HuobiAccountWebSocketClient) send(message interface{}) error {
+	bytes, err := encoding.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	conn := c.conn
+	c.mutex.Unlock()
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	c.notify(&Frame{Outgoing: true, Channel: getString(bytes, "topic"), Payload: RedactPayload(bytes), Time: time.Now()})
+	c.write.Lock()
+	defer c.write.Unlock()
+	return conn.WriteMessage(websocket.TextMessage, bytes)
+}
+
+func (c *HuobiAccountWebSocketClient) notify(frame *Frame) {
+	for _, hook := range c.Hooks {
+		hook(frame)
+	}
+}
+
+func (c *HuobiAccountWebSocketClient) unregister(topic string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.callbacks, topic)
+}
+
+// failPending makes every waiting request fail with ErrNotConnected, the caller must hold the mutex.
+func (c *HuobiAccountWebSocketClient) failPending() {
+	for cid, response := range c.pending {
+		delete(c.pending, cid)
+		close(response)
+	}
+}
+
+func (c *HuobiAccountWebSocketClient) nextCid() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.cid++
+	return strconv.FormatUint(c.cid, 10)
+}
+
+func extractWsApiError(value []byte) error {
+	code, _ := json.GetInt(value, "err-code")
+	if code == 0 {
+		return nil
+	}
+
+	msg := getString(value, "err-msg")
+	apiCode, ok := WsApiCodes[code]
+	if !ok {
+		apiCode = getApiCode(msg)
+	}
+	if apiCode == Unknown && getString(value, "op") == "auth" {
+		apiCode = AuthenticationFailed
+	}
+	return &ApiError{Code: apiCode, Message: msg}
+}
+
+// parseOrderNotification reports false when the order type is missing or unknown.
+func parseOrderNotification(value []byte) (Order, bool) {
+	tradeType, ok := lookupTradeType(getString(value, "data", "order-type"))
+	if !ok {
+		return Order{}, false
+	}
+
+	total := getFloat(value, "data", "order-amount")
+	return Order{
+		Id:          getUint(value, "data", "order-id"),
+		Price:       getFloat(value, "data", "order-price"),
+		TotalAmount: total,
+		TradeAmount: total - getFloat(value, "data", "unfilled-amount"),
+		Symbol:      getString(value, "data", "symbol"),
+		Status:      orderStatuses[getString(value, "data", "order-state")],
+		TradeType:   tradeType,
+		Time:        getUint(value, "data", "created-at"),
+	}, true
+}
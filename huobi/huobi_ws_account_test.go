@@ -0,0 +1,241 @@
+package zb
+
+import (
+	"context"
+	encoding "encoding/json"
+	. "github.com/berryland/x"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type accountServer struct {
+	*httptest.Server
+	mutex  sync.Mutex
+	auths  int
+	topics []string
+	conns  []*websocket.Conn
+}
+
+// newAccountServer acknowledges every op and records the subscribed topics, auth fails for any key but testAccessKey.
+func newAccountServer(t *testing.T) *accountServer {
+	s := &accountServer{}
+	upgrader := websocket.Upgrader{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		s.mutex.Lock()
+		s.conns = append(s.conns, conn)
+		s.mutex.Unlock()
+		defer conn.Close()
+
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var message map[string]string
+			encoding.Unmarshal(data, &message)
+
+			s.mutex.Lock()
+			switch message["op"] {
+			case "auth":
+				if message["AccessKeyId"] != testAccessKey || message["Signature"] == "" {
+					gzipMessage(t, conn, `{"op":"auth","cid":"`+message["cid"]+`","err-code":2002,"err-msg":"auth.fail"}`)
+				} else {
+					s.auths++
+					gzipMessage(t, conn, `{"op":"auth","cid":"`+message["cid"]+`","err-code":0,"data":{"user-id":12345}}`)
+				}
+			case "sub":
+				s.topics = append(s.topics, message["topic"])
+				gzipMessage(t, conn, `{"op":"sub","cid":"`+message["cid"]+`","topic":"`+message["topic"]+`","err-code":0}`)
+			}
+			s.mutex.Unlock()
+		}
+	}))
+	return s
+}
+
+func (s *accountServer) push(t *testing.T, message string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	gzipMessage(t, s.conns[len(s.conns)-1], message)
+}
+
+func newTestAccountWebSocketClient(t *testing.T, s *accountServer, accessKey string) *HuobiAccountWebSocketClient {
+	c := NewAccountWebSocketClient(accessKey, testSecretKey)
+	c.Url = "ws" + strings.TrimPrefix(s.URL, "http") + "/ws/v1"
+	c.Timeout = time.Second
+	return c
+}
+
+func TestHuobiAccountWebSocketClient_SubscribeOrders(t *testing.T) {
+	s := newAccountServer(t)
+	defer s.Close()
+	c := newTestAccountWebSocketClient(t, s, testAccessKey)
+	assert.Nil(t, c.Connect(context.Background()))
+	defer c.Disconnect()
+
+	orders := make(chan Order, 1)
+	assert.Nil(t, c.SubscribeOrders("btcusdt", func(order Order) {
+		orders <- order
+	}))
+	s.push(t, `{"op":"notify","topic":"orders.btcusdt","ts":1522856623232,"data":{"seq-id":94984,"order-id":2039498445,"symbol":"btcusdt","account-id":100077,"order-amount":"0.5","order-price":"10000","created-at":1522856623000,"order-type":"buy-limit","order-source":"api","order-state":"partial-filled","role":"taker","price":"10000","filled-amount":"0.1","unfilled-amount":"0.3","filled-cash-amount":"1000","filled-fees":"0.0002"}}`)
+
+	select {
+	case order := <-orders:
+		assert.Equal(t, Order{Id: 2039498445, Price: 10000, TotalAmount: 0.5, TradeAmount: 0.2, Symbol: "btcusdt", Status: PartiallyFilled, TradeType: Buy, Time: 1522856623000}, order)
+	case <-time.After(time.Second):
+		t.Fatal("no order received")
+	}
+}
+
+func TestHuobiAccountWebSocketClient_SubscribeOrdersUnknownType(t *testing.T) {
+	s := newAccountServer(t)
+	defer s.Close()
+	c := newTestAccountWebSocketClient(t, s, testAccessKey)
+	assert.Nil(t, c.Connect(context.Background()))
+	defer c.Disconnect()
+
+	orders := make(chan Order, 2)
+	assert.Nil(t, c.SubscribeOrders("btcusdt", func(order Order) {
+		orders <- order
+	}))
+	s.push(t, `{"op":"notify","topic":"orders.btcusdt","ts":1522856623232,"data":{"order-id":1,"symbol":"btcusdt","order-state":"submitted"}}`)
+	s.push(t, `{"op":"notify","topic":"orders.btcusdt","ts":1522856623232,"data":{"order-id":2,"symbol":"btcusdt","order-state":"submitted","order-type":"swap-limit"}}`)
+	s.push(t, `{"op":"notify","topic":"orders.btcusdt","ts":1522856623232,"data":{"order-id":3,"symbol":"btcusdt","order-state":"submitted","order-type":"sell-limit"}}`)
+
+	select {
+	case order := <-orders:
+		assert.Equal(t, uint64(3), order.Id)
+		assert.Equal(t, Sell, order.TradeType)
+	case <-time.After(time.Second):
+		t.Fatal("no order received")
+	}
+}
+
+func TestHuobiAccountWebSocketClient_SubscribeAccounts(t *testing.T) {
+	s := newAccountServer(t)
+	defer s.Close()
+	c := newTestAccountWebSocketClient(t, s, testAccessKey)
+	assert.Nil(t, c.Connect(context.Background()))
+	defer c.Disconnect()
+
+	events := make(chan []Asset, 2)
+	assert.Nil(t, c.SubscribeAccounts(func(event string, assets []Asset) {
+		assert.Equal(t, "order.place", event)
+		events <- assets
+	}))
+	s.push(t, `{"op":"notify","topic":"accounts","ts":1522856623232,"data":{"event":"order.place","list":[{"account-id":419013,"currency":"usdt","type":"trade","balance":"500"},{"account-id":419013,"currency":"usdt","type":"frozen","balance":"100"}]}}`)
+	s.push(t, `{"op":"notify","topic":"accounts","ts":1522856623233,"data":{"event":"order.place","list":[{"account-id":419013,"currency":"usdt","type":"trade","balance":"400"}]}}`)
+
+	usdt := Coin{Key: "usdt", EnName: "USDT"}
+	for _, expected := range []Asset{{Available: 500, Freeze: 100, Coin: usdt}, {Available: 400, Freeze: 100, Coin: usdt}} {
+		select {
+		case assets := <-events:
+			assert.Equal(t, []Asset{expected}, assets)
+		case <-time.After(time.Second):
+			t.Fatal("no account update received")
+		}
+	}
+}
+
+func TestHuobiAccountWebSocketClient_Resubscribe(t *testing.T) {
+	s := newAccountServer(t)
+	defer s.Close()
+	c := newTestAccountWebSocketClient(t, s, testAccessKey)
+	assert.Nil(t, c.Connect(context.Background()))
+	defer c.Disconnect()
+	assert.Nil(t, c.SubscribeOrders("btcusdt", func(order Order) {}))
+
+	c.Disconnect()
+	assert.Nil(t, c.Connect(context.Background()))
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	assert.Equal(t, 2, s.auths)
+	assert.Equal(t, []string{"orders.btcusdt", "orders.btcusdt"}, s.topics)
+}
+
+func TestHuobiAccountWebSocketClient_AuthFailed(t *testing.T) {
+	s := newAccountServer(t)
+	defer s.Close()
+	c := newTestAccountWebSocketClient(t, s, "wrong")
+
+	err := c.Connect(context.Background())
+	assert.Equal(t, &ApiError{Code: AuthenticationFailed, Message: "auth.fail"}, err)
+}
+
+func TestHuobiAccountWebSocketClient_Reconnect(t *testing.T) {
+	s := newAccountServer(t)
+	defer s.Close()
+	c := newTestAccountWebSocketClient(t, s, testAccessKey)
+	c.Backoff = NewBackoff(10*time.Millisecond, 100*time.Millisecond)
+	gaps := make(chan string, 1)
+	c.OnGap = func(topic string) {
+		gaps <- topic
+	}
+	assert.Nil(t, c.Connect(context.Background()))
+	defer c.Disconnect()
+
+	orders := make(chan Order, 1)
+	assert.Nil(t, c.SubscribeOrders("btcusdt", func(order Order) {
+		orders <- order
+	}))
+	s.mutex.Lock()
+	s.conns[0].Close()
+	s.mutex.Unlock()
+
+	select {
+	case topic := <-gaps:
+		assert.Equal(t, "orders.btcusdt", topic)
+	case <-time.After(time.Second):
+		t.Fatal("no gap reported")
+	}
+	s.push(t, `{"op":"notify","topic":"orders.btcusdt","ts":1522856623232,"data":{"order-id":2039498445,"symbol":"btcusdt","order-state":"submitted","order-type":"sell-limit"}}`)
+	select {
+	case order := <-orders:
+		assert.Equal(t, uint64(2039498445), order.Id)
+	case <-time.After(time.Second):
+		t.Fatal("no order received after reconnect")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	assert.Equal(t, 2, s.auths)
+	assert.Equal(t, []string{"orders.btcusdt", "orders.btcusdt"}, s.topics)
+}
+
+func TestExtractWsApiError(t *testing.T) {
+	assert.Nil(t, extractWsApiError([]byte(`{"op":"sub","err-code":0}`)))
+	assert.Equal(t, &ApiError{Code: UserNotFound, Message: "nonexist.account"}, extractWsApiError([]byte(`{"op":"sub","err-code":2001,"err-msg":"nonexist.account"}`)))
+	assert.Equal(t, &ApiError{Code: InvalidAddress, Message: "invalid-address"}, extractWsApiError([]byte(`{"op":"req","err-code":9999,"err-msg":"invalid-address"}`)))
+	assert.Equal(t, &ApiError{Code: Unknown, Message: "unknown"}, extractWsApiError([]byte(`{"op":"req","err-code":9999,"err-msg":"unknown"}`)))
+}
+
+func TestHuobiAccountWebSocketClient_HooksRedactSecrets(t *testing.T) {
+	s := newAccountServer(t)
+	defer s.Close()
+	c := newTestAccountWebSocketClient(t, s, testAccessKey)
+	var payloads []string
+	c.Hooks = []WsHook{func(frame *Frame) {
+		if frame.Outgoing {
+			payloads = append(payloads, string(frame.Payload))
+		}
+	}}
+	assert.Nil(t, c.Connect(context.Background()))
+	defer c.Disconnect()
+
+	assert.Equal(t, 1, len(payloads))
+	assert.Contains(t, payloads[0], `"AccessKeyId":"***"`)
+	assert.Contains(t, payloads[0], `"Signature":"***"`)
+	assert.NotContains(t, payloads[0], testAccessKey)
+}
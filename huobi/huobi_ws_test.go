@@ -0,0 +1,319 @@
+package zb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	. "github.com/berryland/x"
+	"github.com/berryland/x/xtest"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func gzipMessage(t *testing.T, conn *websocket.Conn, message string) {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	writer.Write([]byte(message))
+	writer.Close()
+	if err := conn.WriteMessage(websocket.BinaryMessage, buffer.Bytes()); err != nil {
+		t.Error(err)
+	}
+}
+
+// newTestWebSocketClient starts a server which hands every decoded client message to handler.
+func newTestWebSocketClient(t *testing.T, handler func(conn *websocket.Conn, message map[string]interface{})) (*HuobiWebSocketClient, func()) {
+	url, shutdown := xtest.NewWebSocketServer(t, handler)
+	c := NewWebSocketClient()
+	c.Url = url
+	c.Timeout = time.Second
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return c, func() {
+		c.Disconnect()
+		shutdown()
+	}
+}
+
+func TestHuobiWebSocketClient_SubscribeKline(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		if message["sub"] == "market.btcusdt.kline.1min" {
+			gzipMessage(t, conn, `{"id":"`+message["id"].(string)+`","status":"ok","subbed":"market.btcusdt.kline.1min","ts":1516536000000}`)
+			gzipMessage(t, conn, `{"ch":"market.btcusdt.kline.1min","ts":1516536001000,"tick":{"id":1516536000,"open":100,"close":110,"low":90,"high":120,"amount":1.5,"vol":150,"count":3}}`)
+		}
+	})
+	defer shutdown()
+
+	klines := make(chan Kline, 1)
+	_, err := c.SubscribeKline("btcusdt", "1min", func(kline Kline) {
+		klines <- kline
+	})
+	assert.Nil(t, err)
+
+	select {
+	case kline := <-klines:
+		assert.Equal(t, Kline{Time: 1516536000000, Open: 100, High: 120, Low: 90, Close: 110, Amount: 1.5}, kline)
+	case <-time.After(time.Second):
+		t.Fatal("no kline received")
+	}
+}
+
+func TestHuobiWebSocketClient_SubscribeRejected(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		gzipMessage(t, conn, `{"id":"`+message["id"].(string)+`","status":"error","err-code":"bad-request","err-msg":"invalid topic market.foo.detail"}`)
+	})
+	defer shutdown()
+
+	_, err := c.SubscribeDetail("foo", func(ticker Ticker) {})
+	assert.Equal(t, &ApiError{Code: InvalidArgument, Message: "invalid topic market.foo.detail"}, err)
+}
+
+func TestHuobiWebSocketClient_Ping(t *testing.T) {
+	pongs := make(chan float64, 1)
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		if pong, ok := message["pong"]; ok {
+			pongs <- pong.(float64)
+			return
+		}
+		gzipMessage(t, conn, `{"ping":1492420473027}`)
+		gzipMessage(t, conn, `{"id":"`+message["id"].(string)+`","status":"ok","subbed":"market.btcusdt.detail"}`)
+	})
+	defer shutdown()
+
+	var channels []string
+	c.Hooks = []WsHook{func(frame *Frame) {
+		if !frame.Outgoing {
+			channels = append(channels, frame.Channel)
+		}
+	}}
+	_, err := c.SubscribeDetail("btcusdt", func(ticker Ticker) {})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"", "market.btcusdt.detail"}, channels)
+
+	select {
+	case pong := <-pongs:
+		assert.Equal(t, float64(1492420473027), pong)
+	case <-time.After(time.Second):
+		t.Fatal("no pong received")
+	}
+}
+
+func TestHuobiWebSocketClient_RequestKlines(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		assert.Equal(t, "market.btcusdt.kline.1min", message["req"])
+		assert.Equal(t, float64(1516536000), message["from"])
+		assert.Equal(t, float64(1516536060), message["to"])
+		gzipMessage(t, conn, `{"id":"`+message["id"].(string)+`","status":"ok","rep":"market.btcusdt.kline.1min","data":[{"id":1516536000,"open":100,"close":110,"low":90,"high":120,"amount":1.5},{"id":1516536060,"open":110,"close":105,"low":100,"high":115,"amount":2}]}`)
+	})
+	defer shutdown()
+
+	klines, err := c.RequestKlines("btcusdt", "1min", 1516536000, 1516536060)
+	assert.Nil(t, err)
+	assert.Equal(t, []Kline{
+		{Time: 1516536000000, Open: 100, High: 120, Low: 90, Close: 110, Amount: 1.5},
+		{Time: 1516536060000, Open: 110, High: 115, Low: 100, Close: 105, Amount: 2},
+	}, klines)
+}
+
+func TestHuobiWebSocketClient_GetKlineRange(t *testing.T) {
+	var ranges [][2]uint64
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		from, to := uint64(message["from"].(float64)), uint64(message["to"].(float64))
+		ranges = append(ranges, [2]uint64{from, to})
+		var data []string
+		for id := from; id <= to; id += 60 {
+			data = append(data, `{"id":`+strconv.FormatUint(id, 10)+`,"open":1,"close":1,"low":1,"high":1,"amount":1}`)
+		}
+		gzipMessage(t, conn, `{"id":"`+message["id"].(string)+`","status":"ok","rep":"market.btcusdt.kline.1min","data":[`+strings.Join(data, ",")+`]}`)
+	})
+	defer shutdown()
+
+	start := uint64(1483228800000)
+	r, err := NewKlineRangeFetcher(c).Fetch(ParsePair("btc_usdt"), "1min", start, start+700*60000)
+	assert.Nil(t, err)
+	assert.Equal(t, 700, len(r.Klines))
+	assert.Empty(t, r.Gaps)
+	assert.Equal(t, start, r.Klines[0].Time)
+	assert.Equal(t, start+699*60000, r.Klines[699].Time)
+	assert.Equal(t, [][2]uint64{
+		{1483228800, 1483246799},
+		{1483246800, 1483264799},
+		{1483264800, 1483270799},
+	}, ranges)
+}
+
+func TestHuobiWebSocketClient_RequestTimeout(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {})
+	defer shutdown()
+
+	c.Timeout = 50 * time.Millisecond
+	_, err := c.RequestDetail("btcusdt")
+	assert.Equal(t, ErrTimeout, err)
+}
+
+func TestHuobiWebSocketClient_Unsubscribe(t *testing.T) {
+	messages := make(chan map[string]interface{}, 2)
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		messages <- message
+		gzipMessage(t, conn, `{"id":"`+message["id"].(string)+`","status":"ok"}`)
+	})
+	defer shutdown()
+
+	s, err := c.SubscribeTrades("btcusdt", func(trades []Trade) {})
+	assert.Nil(t, err)
+	assert.Nil(t, s.Unsubscribe())
+	assert.Nil(t, s.Unsubscribe())
+
+	assert.Equal(t, "market.btcusdt.trade.detail", (<-messages)["sub"])
+	assert.Equal(t, "market.btcusdt.trade.detail", (<-messages)["unsub"])
+	assert.Empty(t, messages)
+}
+
+func TestHuobiWebSocketClient_FanOut(t *testing.T) {
+	subs := make(chan string, 2)
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		if message["sub"] == nil {
+			gzipMessage(t, conn, `{"ch":"market.btcusdt.trade.detail","ts":1516536001000,"tick":{"data":[{"id":1,"amount":0.5,"price":11000,"direction":"buy","ts":1516536001000}]}}`)
+			return
+		}
+		subs <- message["sub"].(string)
+		gzipMessage(t, conn, `{"id":"`+message["id"].(string)+`","status":"ok","subbed":"market.btcusdt.trade.detail"}`)
+	})
+	defer shutdown()
+
+	trades := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		i := i
+		_, err := c.SubscribeTrades("btcusdt", func([]Trade) { trades <- i })
+		assert.Nil(t, err)
+	}
+	c.send("", map[string]string{"push": "trades"})
+
+	var received []int
+	for len(received) < 2 {
+		select {
+		case i := <-trades:
+			received = append(received, i)
+		case <-time.After(time.Second):
+			t.Fatal("trades were not fanned out to every listener")
+		}
+	}
+	assert.ElementsMatch(t, []int{0, 1}, received)
+	assert.Equal(t, "market.btcusdt.trade.detail", <-subs)
+	assert.Empty(t, subs)
+}
+
+func TestHuobiWebSocketClient_UnsubscribeListener(t *testing.T) {
+	messages := make(chan map[string]interface{}, 3)
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		if message["push"] != nil {
+			gzipMessage(t, conn, `{"ch":"market.btcusdt.trade.detail","ts":1516536001000,"tick":{"data":[{"id":1,"amount":0.5,"price":11000,"direction":"buy","ts":1516536001000}]}}`)
+			return
+		}
+		messages <- message
+		gzipMessage(t, conn, `{"id":"`+message["id"].(string)+`","status":"ok"}`)
+	})
+	defer shutdown()
+
+	first, second := make(chan []Trade, 1), make(chan []Trade, 1)
+	s1, err := c.SubscribeTrades("btcusdt", func(trades []Trade) { first <- trades })
+	assert.Nil(t, err)
+	s2, err := c.SubscribeTrades("btcusdt", func(trades []Trade) { second <- trades })
+	assert.Nil(t, err)
+
+	assert.Nil(t, s1.Unsubscribe())
+	c.send("", map[string]string{"push": "trades"})
+	select {
+	case <-second:
+	case <-time.After(time.Second):
+		t.Fatal("the remaining listener was stopped")
+	}
+	assert.Empty(t, first)
+
+	assert.Nil(t, s2.Unsubscribe())
+	assert.Equal(t, "market.btcusdt.trade.detail", (<-messages)["sub"])
+	assert.Equal(t, "market.btcusdt.trade.detail", (<-messages)["unsub"])
+	assert.Empty(t, messages)
+}
+
+func TestHuobiWebSocketClient_Reconnect(t *testing.T) {
+	subs := make(chan string, 3)
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		if message["push"] != nil {
+			gzipMessage(t, conn, `{"ch":"market.btcusdt.detail","ts":1516536001000,"tick":{"open":100,"close":110,"low":90,"high":120,"amount":1.5,"vol":150}}`)
+			return
+		}
+		subs <- message["sub"].(string)
+		gzipMessage(t, conn, `{"id":"`+message["id"].(string)+`","status":"ok","subbed":"`+message["sub"].(string)+`"}`)
+	})
+	defer shutdown()
+
+	_, err := c.SubscribeDetail("btcusdt", func(ticker Ticker) {})
+	assert.Nil(t, err)
+	c.Disconnect()
+	assert.Nil(t, c.Connect(context.Background()))
+
+	tickers := make(chan Ticker, 1)
+	_, err = c.SubscribeDetail("btcusdt", func(ticker Ticker) { tickers <- ticker })
+	assert.Nil(t, err)
+	c.send("", map[string]string{"push": "detail"})
+
+	select {
+	case ticker := <-tickers:
+		assert.Equal(t, 110.0, ticker.Last)
+	case <-time.After(time.Second):
+		t.Fatal("no ticker received after reconnecting")
+	}
+	assert.Equal(t, "market.btcusdt.detail", <-subs)
+	assert.Equal(t, "market.btcusdt.detail", <-subs)
+	assert.Empty(t, subs)
+}
+
+func TestHuobiWebSocketClient_RequestDropped(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		conn.Close()
+	})
+	defer shutdown()
+
+	start := time.Now()
+	_, err := c.RequestDetail("btcusdt")
+	assert.Equal(t, ErrNotConnected, err)
+	assert.True(t, time.Since(start) < c.Timeout)
+}
+
+func TestHuobiWebSocketClient_UnsubscribeFromCallback(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		gzipMessage(t, conn, `{"id":"`+message["id"].(string)+`","status":"ok"}`)
+		if message["sub"] != nil {
+			gzipMessage(t, conn, `{"ch":"market.btcusdt.trade.detail","ts":1516536001000,"tick":{"data":[{"id":1,"amount":0.5,"price":11000,"direction":"buy","ts":1516536001000}]}}`)
+		}
+	})
+	defer shutdown()
+
+	errs := make(chan error, 1)
+	var s Subscription
+	ready := make(chan struct{})
+	s, err := c.SubscribeTrades("btcusdt", func(trades []Trade) {
+		<-ready
+		errs <- s.Unsubscribe()
+	})
+	assert.Nil(t, err)
+	close(ready)
+
+	select {
+	case err := <-errs:
+		assert.Nil(t, err)
+	case <-time.After(c.Timeout / 2):
+		t.Fatal("Unsubscribe from a callback did not return")
+	}
+}
+
+func TestHuobiWebSocketClient_NotConnected(t *testing.T) {
+	c := NewWebSocketClient()
+	_, err := c.SubscribeDetail("btcusdt", func(ticker Ticker) {})
+	assert.Equal(t, ErrNotConnected, err)
+}
@@ -2,8 +2,70 @@ package zb
 
 import (
 	. "github.com/berryland/x"
+	json "github.com/buger/jsonparser"
+	"strconv"
 )
 
 func parseSymbol(pair Pair) string {
 	return pair.Base.Symbol + pair.Valuation.Symbol
 }
+
+// marshalTrades skips the trades without a known direction.
+func marshalTrades(value []byte, keys ...string) []Trade {
+	var trades []Trade
+	json.ArrayEach(value, func(value []byte, dataType json.ValueType, offset int, err error) {
+		id, err := json.GetInt(value, "trade-id")
+		if err != nil {
+			id, _ = json.GetInt(value, "id")
+		}
+		price, _ := json.GetFloat(value, "price")
+		amount, _ := json.GetFloat(value, "amount")
+		direction, ok := lookupTradeType(getString(value, "direction"))
+		if !ok {
+			return
+		}
+		time, _ := json.GetInt(value, "ts")
+		trades = append(trades, Trade{Id: uint64(id), TradeType: direction, Price: price, Amount: amount, Time: uint64(time)})
+	}, keys...)
+	return trades
+}
+
+func getString(value []byte, keys ...string) string {
+	s, _ := json.GetString(value, keys...)
+	return s
+}
+
+func getFloat(value []byte, keys ...string) float64 {
+	if f, err := json.GetFloat(value, keys...); err == nil {
+		return f
+	}
+
+	f, _ := strconv.ParseFloat(getString(value, keys...), 64)
+	return f
+}
+
+func getUint(value []byte, keys ...string) uint64 {
+	if i, err := json.GetInt(value, keys...); err == nil {
+		return uint64(i)
+	}
+
+	i, _ := strconv.ParseUint(getString(value, keys...), 10, 64)
+	return i
+}
+
+func marshalKline(value []byte) Kline {
+	open, _ := json.GetFloat(value, "open")
+	high, _ := json.GetFloat(value, "high")
+	low, _ := json.GetFloat(value, "low")
+	close, _ := json.GetFloat(value, "close")
+	amount, _ := json.GetFloat(value, "amount")
+	return Kline{Time: getUint(value, "id") * 1000, Open: open, High: high, Low: low, Close: close, Amount: amount}
+}
+
+func marshalDetail(value []byte, time uint64) Ticker {
+	close, _ := json.GetFloat(value, "close")
+	high, _ := json.GetFloat(value, "high")
+	low, _ := json.GetFloat(value, "low")
+	amount, _ := json.GetFloat(value, "amount")
+	return Ticker{Amount: amount, High: high, Low: low, Last: close, Time: time}
+}
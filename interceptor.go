@@ -0,0 +1,59 @@
+package x
+
+import (
+	json "github.com/buger/jsonparser"
+	"log"
+	"time"
+)
+
+// Frame describes a websocket message sent or received by a client.
+type Frame struct {
+	Outgoing bool
+	Channel  string
+	Payload  []byte
+	Time     time.Time
+}
+
+type WsHook func(frame *Frame)
+
+// RedactPayload masks the top level SecretParams of the json object payload without modifying it, clients pass
+// outgoing frames through it before handing them to their hooks.
+func RedactPayload(payload []byte) []byte {
+	redacted := payload
+	for key := range SecretParams {
+		if _, _, _, err := json.Get(redacted, key); err != nil {
+			continue
+		}
+		if value, err := json.Set(redacted, []byte(`"***"`), key); err == nil {
+			redacted = value
+		}
+	}
+	return redacted
+}
+
+// LogInterceptor writes one key=value line per call, secret parameters are redacted.
+func LogInterceptor(logger *log.Logger) Interceptor {
+	return func(call *Call, next Handler) error {
+		err := next(call)
+		params := BuildUrl(call.Url, call.Query.Redact()).RawQuery
+		if err != nil {
+			logger.Printf("method=%s endpoint=%s params=%q status=%d code=%v latency=%s bytes=%d error=%q", call.Method, call.Url, params, call.Status, call.Code, call.Latency, len(call.Response), err.Error())
+		} else {
+			logger.Printf("method=%s endpoint=%s params=%q status=%d code=%v latency=%s bytes=%d", call.Method, call.Url, params, call.Status, call.Code, call.Latency, len(call.Response))
+		}
+		return err
+	}
+}
+
+func LogWsHook(logger *log.Logger) WsHook {
+	return func(frame *Frame) {
+		logger.Printf("direction=%s channel=%s bytes=%d", direction(frame), frame.Channel, len(frame.Payload))
+	}
+}
+
+func direction(frame *Frame) string {
+	if frame.Outgoing {
+		return "send"
+	}
+	return "receive"
+}
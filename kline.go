@@ -0,0 +1,161 @@
+package x
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+const defaultKlinePageSize = 1000
+
+var periods = map[string]time.Duration{
+	"1min":   time.Minute,
+	"3min":   3 * time.Minute,
+	"5min":   5 * time.Minute,
+	"15min":  15 * time.Minute,
+	"30min":  30 * time.Minute,
+	"60min":  time.Hour,
+	"1hour":  time.Hour,
+	"2hour":  2 * time.Hour,
+	"4hour":  4 * time.Hour,
+	"6hour":  6 * time.Hour,
+	"12hour": 12 * time.Hour,
+	"1day":   24 * time.Hour,
+	"3day":   3 * 24 * time.Hour,
+	"1week":  7 * 24 * time.Hour,
+}
+
+// ParsePeriod returns the length of a kline period, periods without a fixed length such as 1mon are rejected.
+func ParsePeriod(period string) (time.Duration, error) {
+	if d, ok := periods[period]; ok {
+		return d, nil
+	}
+	return 0, errors.New("Unsupported kline period: " + period)
+}
+
+// ErrRangeUnavailable is returned by clients whose klines do not reach back to the requested since.
+var ErrRangeUnavailable = errors.New("Klines are not available from the requested time")
+
+// KlineRangeClient returns the klines opened in [from, to), both in milliseconds, for exchanges able to query a time range.
+type KlineRangeClient interface {
+	GetKlineRange(pair Pair, period string, from, to uint64) ([]Kline, error)
+}
+
+// Gap is a time range [From, To) in which no kline was returned.
+type Gap struct {
+	From uint64
+	To   uint64
+}
+
+type KlineRange struct {
+	Klines []Kline
+	Gaps   []Gap
+}
+
+// KlineFetcher pages through HttpApiClient.GetKlines to cover time ranges longer than a single request allows. When
+// Ranges is set it is queried window by window instead, which suits exchanges whose GetKlines only returns the latest
+// klines.
+type KlineFetcher struct {
+	Client   HttpApiClient
+	Ranges   KlineRangeClient
+	PageSize uint16
+	Limiter  *Limiter
+}
+
+func NewKlineFetcher(client HttpApiClient) *KlineFetcher {
+	f := &KlineFetcher{Client: client, PageSize: defaultKlinePageSize}
+	if ranges, ok := client.(KlineRangeClient); ok {
+		f.Ranges = ranges
+	}
+	return f
+}
+
+func NewKlineRangeFetcher(ranges KlineRangeClient) *KlineFetcher {
+	return &KlineFetcher{Ranges: ranges, PageSize: defaultKlinePageSize}
+}
+
+// Fetch returns the klines opened in [start, end) in ascending order without duplicates, together with the gaps between them.
+func (f *KlineFetcher) Fetch(pair Pair, period string, start, end uint64) (KlineRange, error) {
+	d, err := ParsePeriod(period)
+	if err != nil {
+		return KlineRange{}, err
+	}
+	step := uint64(d / time.Millisecond)
+	size := f.PageSize
+	if size == 0 {
+		size = defaultKlinePageSize
+	}
+
+	klines := map[uint64]Kline{}
+	add := func(page []Kline) {
+		for _, k := range page {
+			if _, ok := klines[k.Time]; !ok && k.Time >= start && k.Time < end {
+				klines[k.Time] = k
+			}
+		}
+	}
+
+	for cursor := start; cursor < end; {
+		f.Limiter.Wait()
+		if f.Ranges != nil {
+			next := cursor + uint64(size)*step
+			if next > end {
+				next = end
+			}
+			page, err := f.Ranges.GetKlineRange(pair, period, cursor, next)
+			if err != nil {
+				return KlineRange{}, err
+			}
+			add(page)
+			cursor = next
+			continue
+		}
+
+		page, err := f.Client.GetKlines(pair, period, cursor, size)
+		if err != nil {
+			return KlineRange{}, err
+		}
+		add(page)
+
+		next := cursor
+		for _, k := range page {
+			if k.Time+step > next {
+				next = k.Time + step
+			}
+		}
+		if next <= cursor {
+			break
+		}
+		cursor = next
+	}
+
+	r := KlineRange{Klines: make([]Kline, 0, len(klines))}
+	for _, k := range klines {
+		r.Klines = append(r.Klines, k)
+	}
+	sort.Slice(r.Klines, func(i, j int) bool {
+		return r.Klines[i].Time < r.Klines[j].Time
+	})
+	r.Gaps = findGaps(r.Klines, step, start, end)
+	return r, nil
+}
+
+func findGaps(klines []Kline, step, start, end uint64) []Gap {
+	if len(klines) == 0 {
+		return []Gap{{From: start, To: end}}
+	}
+
+	var gaps []Gap
+	if klines[0].Time-start >= step {
+		gaps = append(gaps, Gap{From: start, To: klines[0].Time})
+	}
+	for i := 1; i < len(klines); i++ {
+		if klines[i].Time-klines[i-1].Time > step {
+			gaps = append(gaps, Gap{From: klines[i-1].Time + step, To: klines[i].Time})
+		}
+	}
+	if last := klines[len(klines)-1].Time + step; end > last && end-last >= step {
+		gaps = append(gaps, Gap{From: last, To: end})
+	}
+	return gaps
+}
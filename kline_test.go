@@ -0,0 +1,131 @@
+package x
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const minute = 60000
+
+type fakeKlineClient struct {
+	times []uint64
+	calls []uint64
+}
+
+func (c *fakeKlineClient) GetTicker(pair Pair) (Ticker, error) {
+	return Ticker{}, nil
+}
+
+func (c *fakeKlineClient) GetAllTickers() (map[Pair]Ticker, error) {
+	return map[Pair]Ticker{}, nil
+}
+
+// GetKlines returns up to size klines from since, repeating the one before since like an overlapping page would.
+func (c *fakeKlineClient) GetKlines(pair Pair, period string, since uint64, size uint16) ([]Kline, error) {
+	c.calls = append(c.calls, since)
+	var klines []Kline
+	for _, t := range c.times {
+		if t+minute >= since && len(klines) < int(size) {
+			klines = append(klines, Kline{Time: t, Close: float64(t / minute)})
+		}
+	}
+	return klines, nil
+}
+
+func TestParsePeriod(t *testing.T) {
+	d, err := ParsePeriod("15min")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(15*minute), d.Nanoseconds()/1e6)
+
+	_, err = ParsePeriod("1mon")
+	assert.NotNil(t, err)
+}
+
+func TestKlineFetcher_Fetch(t *testing.T) {
+	client := &fakeKlineClient{}
+	for i := uint64(0); i < 100; i++ {
+		if i < 40 || i >= 45 {
+			client.times = append(client.times, i*minute)
+		}
+	}
+
+	f := NewKlineFetcher(client)
+	f.PageSize = 30
+	r, err := f.Fetch(ParsePair("btc_usdt"), "1min", 10*minute, 90*minute)
+	assert.Nil(t, err)
+	assert.Equal(t, 75, len(r.Klines))
+	assert.Equal(t, uint64(10*minute), r.Klines[0].Time)
+	assert.Equal(t, uint64(89*minute), r.Klines[74].Time)
+	for i := 1; i < len(r.Klines); i++ {
+		assert.True(t, r.Klines[i].Time > r.Klines[i-1].Time)
+	}
+	assert.Equal(t, []Gap{{From: 40 * minute, To: 45 * minute}}, r.Gaps)
+	assert.Equal(t, []uint64{10 * minute, 39 * minute, 73 * minute}, client.calls)
+}
+
+func TestKlineFetcher_FetchBeyondData(t *testing.T) {
+	client := &fakeKlineClient{times: []uint64{5 * minute, 6 * minute, 7 * minute}}
+	r, err := NewKlineFetcher(client).Fetch(ParsePair("btc_usdt"), "1min", 0, 20*minute)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(r.Klines))
+	assert.Equal(t, []Gap{{From: 0, To: 5 * minute}, {From: 8 * minute, To: 20 * minute}}, r.Gaps)
+	assert.Equal(t, []uint64{0, 8 * minute}, client.calls)
+}
+
+func TestKlineFetcher_FetchEmpty(t *testing.T) {
+	r, err := NewKlineFetcher(&fakeKlineClient{}).Fetch(ParsePair("btc_usdt"), "5min", 0, 20*minute)
+	assert.Nil(t, err)
+	assert.Empty(t, r.Klines)
+	assert.Equal(t, []Gap{{From: 0, To: 20 * minute}}, r.Gaps)
+}
+
+type failingKlineClient struct {
+	fakeKlineClient
+}
+
+func (c *failingKlineClient) GetKlines(pair Pair, period string, since uint64, size uint16) ([]Kline, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestKlineFetcher_FetchError(t *testing.T) {
+	_, err := NewKlineFetcher(&failingKlineClient{}).Fetch(ParsePair("btc_usdt"), "1min", 0, minute)
+	assert.EqualError(t, err, "unavailable")
+
+	_, err = NewKlineFetcher(&failingKlineClient{}).Fetch(ParsePair("btc_usdt"), "1mon", 0, minute)
+	assert.EqualError(t, err, "Unsupported kline period: 1mon")
+}
+
+type fakeKlineRangeClient struct {
+	fakeKlineClient
+	ranges [][2]uint64
+}
+
+func (c *fakeKlineRangeClient) GetKlineRange(pair Pair, period string, from, to uint64) ([]Kline, error) {
+	c.ranges = append(c.ranges, [2]uint64{from, to})
+	var klines []Kline
+	for _, t := range c.times {
+		if t >= from && t < to {
+			klines = append(klines, Kline{Time: t})
+		}
+	}
+	return klines, nil
+}
+
+func TestKlineFetcher_FetchRanges(t *testing.T) {
+	client := &fakeKlineRangeClient{}
+	for i := uint64(0); i < 100; i++ {
+		if i < 40 || i >= 45 {
+			client.times = append(client.times, i*minute)
+		}
+	}
+
+	f := NewKlineFetcher(client)
+	f.PageSize = 30
+	r, err := f.Fetch(ParsePair("btc_usdt"), "1min", 10*minute, 90*minute)
+	assert.Nil(t, err)
+	assert.Equal(t, 75, len(r.Klines))
+	assert.Equal(t, []Gap{{From: 40 * minute, To: 45 * minute}}, r.Gaps)
+	assert.Equal(t, [][2]uint64{{10 * minute, 40 * minute}, {40 * minute, 70 * minute}, {70 * minute, 90 * minute}}, client.ranges)
+	assert.Empty(t, client.calls)
+}
@@ -0,0 +1,35 @@
+package x
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter spaces out calls so that they happen at most once per Interval.
+type Limiter struct {
+	Interval time.Duration
+	mutex    sync.Mutex
+	next     time.Time
+}
+
+func NewLimiter(interval time.Duration) *Limiter {
+	return &Limiter{Interval: interval}
+}
+
+// Wait blocks until the caller is allowed to make the next call, a nil Limiter never blocks.
+func (l *Limiter) Wait() {
+	if l == nil {
+		return
+	}
+
+	l.mutex.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.Interval)
+	l.mutex.Unlock()
+
+	time.Sleep(at.Sub(now))
+}
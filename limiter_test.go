@@ -0,0 +1,16 @@
+package x
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Wait(t *testing.T) {
+	l := NewLimiter(20 * time.Millisecond)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		l.Wait()
+	}
+	assert.True(t, time.Since(start) >= 60*time.Millisecond)
+}
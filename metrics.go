@@ -0,0 +1,160 @@
+package x
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metrics collects call and frame statistics and serves them in the Prometheus text format.
+type Metrics struct {
+	mutex  sync.Mutex
+	calls  map[callKey]*callStats
+	frames map[frameKey]*frameStats
+}
+
+type callKey struct {
+	method   string
+	endpoint string
+	status   int
+	code     string
+}
+
+type callStats struct {
+	count   uint64
+	bytes   uint64
+	seconds float64
+}
+
+type frameKey struct {
+	direction string
+	channel   string
+}
+
+type frameStats struct {
+	count uint64
+	bytes uint64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{calls: map[callKey]*callStats{}, frames: map[frameKey]*frameStats{}}
+}
+
+// Intercept is an Interceptor recording every call made by a HttpClient.
+func (m *Metrics) Intercept(call *Call, next Handler) error {
+	err := next(call)
+
+	code := call.Code.String()
+	if _, ok := err.(*ApiError); err != nil && !ok {
+		code = "error"
+	}
+	key := callKey{method: call.Method, endpoint: endpoint(call.Url), status: call.Status, code: code}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	stats, ok := m.calls[key]
+	if !ok {
+		stats = &callStats{}
+		m.calls[key] = stats
+	}
+	stats.count++
+	stats.bytes += uint64(len(call.Response))
+	stats.seconds += call.Latency.Seconds()
+	return err
+}
+
+// Observe is a WsHook recording every frame sent or received by a websocket client.
+func (m *Metrics) Observe(frame *Frame) {
+	key := frameKey{direction: direction(frame), channel: frame.Channel}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	stats, ok := m.frames[key]
+	if !ok {
+		stats = &frameStats{}
+		m.frames[key] = stats
+	}
+	stats.count++
+	stats.bytes += uint64(len(frame.Payload))
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WriteTo(w)
+}
+
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var lines []string
+	callKeys := make([]callKey, 0, len(m.calls))
+	for k := range m.calls {
+		callKeys = append(callKeys, k)
+	}
+	sort.Slice(callKeys, func(i, j int) bool {
+		return callLabels(callKeys[i]) < callLabels(callKeys[j])
+	})
+	frameKeys := make([]frameKey, 0, len(m.frames))
+	for k := range m.frames {
+		frameKeys = append(frameKeys, k)
+	}
+	sort.Slice(frameKeys, func(i, j int) bool {
+		return frameLabels(frameKeys[i]) < frameLabels(frameKeys[j])
+	})
+
+	lines = append(lines, "# HELP x_http_requests_total Number of api calls.", "# TYPE x_http_requests_total counter")
+	for _, k := range callKeys {
+		lines = append(lines, "x_http_requests_total"+callLabels(k)+" "+strconv.FormatUint(m.calls[k].count, 10))
+	}
+	lines = append(lines, "# HELP x_http_request_duration_seconds Latency of api calls.", "# TYPE x_http_request_duration_seconds summary")
+	for _, k := range callKeys {
+		lines = append(lines, "x_http_request_duration_seconds_sum"+callLabels(k)+" "+strconv.FormatFloat(m.calls[k].seconds, 'f', -1, 64))
+		lines = append(lines, "x_http_request_duration_seconds_count"+callLabels(k)+" "+strconv.FormatUint(m.calls[k].count, 10))
+	}
+	lines = append(lines, "# HELP x_http_response_bytes_total Bytes received from api calls.", "# TYPE x_http_response_bytes_total counter")
+	for _, k := range callKeys {
+		lines = append(lines, "x_http_response_bytes_total"+callLabels(k)+" "+strconv.FormatUint(m.calls[k].bytes, 10))
+	}
+	lines = append(lines, "# HELP x_ws_frames_total Number of websocket frames.", "# TYPE x_ws_frames_total counter")
+	for _, k := range frameKeys {
+		lines = append(lines, "x_ws_frames_total"+frameLabels(k)+" "+strconv.FormatUint(m.frames[k].count, 10))
+	}
+	lines = append(lines, "# HELP x_ws_frame_bytes_total Bytes of websocket frames.", "# TYPE x_ws_frame_bytes_total counter")
+	for _, k := range frameKeys {
+		lines = append(lines, "x_ws_frame_bytes_total"+frameLabels(k)+" "+strconv.FormatUint(m.frames[k].bytes, 10))
+	}
+
+	n, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return int64(n), err
+}
+
+// endpoint replaces the numeric segments of the path of url, like the order id of huobi's
+// order/orders/{id}/submitcancel, so that every order does not add a series of its own.
+func endpoint(url string) string {
+	segments := strings.Split(url, "/")
+	for i, segment := range segments {
+		if segment != "" && strings.Trim(segment, "0123456789") == "" {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func callLabels(k callKey) string {
+	return fmt.Sprintf(`{method="%s",endpoint="%s",status="%d",code="%s"}`, escapeLabel(k.method), escapeLabel(k.endpoint), k.status, escapeLabel(k.code))
+}
+
+func frameLabels(k frameKey) string {
+	return fmt.Sprintf(`{direction="%s",channel="%s"}`, escapeLabel(k.direction), escapeLabel(k.channel))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
@@ -0,0 +1,57 @@
+package x
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	ok := func(call *Call) error {
+		call.Status, call.Response, call.Latency = 200, []byte("{}"), 500*time.Millisecond
+		return nil
+	}
+	failed := func(call *Call) error {
+		call.Status, call.Code = 200, TooFrequent
+		return &ApiError{Code: TooFrequent}
+	}
+	m.Intercept(&Call{Method: "GET", Url: "http://api.zb.com/data/v1/ticker"}, ok)
+	m.Intercept(&Call{Method: "GET", Url: "http://api.zb.com/data/v1/ticker"}, ok)
+	m.Intercept(&Call{Method: "GET", Url: "https://trade.zb.com/api/order"}, failed)
+	m.Observe(&Frame{Outgoing: true, Channel: "btcusdt_ticker", Payload: []byte("12345")})
+
+	recorder := httptest.NewRecorder()
+	m.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := recorder.Body.String()
+
+	assert.True(t, strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain"))
+	assert.Contains(t, body, "# TYPE x_http_requests_total counter\n")
+	assert.Contains(t, body, `x_http_requests_total{method="GET",endpoint="http://api.zb.com/data/v1/ticker",status="200",code="OK"} 2`+"\n")
+	assert.Contains(t, body, `x_http_requests_total{method="GET",endpoint="https://trade.zb.com/api/order",status="200",code="TooFrequent"} 1`+"\n")
+	assert.Contains(t, body, `x_http_request_duration_seconds_sum{method="GET",endpoint="http://api.zb.com/data/v1/ticker",status="200",code="OK"} 1`+"\n")
+	assert.Contains(t, body, `x_http_response_bytes_total{method="GET",endpoint="http://api.zb.com/data/v1/ticker",status="200",code="OK"} 4`+"\n")
+	assert.Contains(t, body, `x_ws_frames_total{direction="send",channel="btcusdt_ticker"} 1`+"\n")
+	assert.Contains(t, body, `x_ws_frame_bytes_total{direction="send",channel="btcusdt_ticker"} 5`+"\n")
+}
+
+func TestEscapeLabel(t *testing.T) {
+	assert.Equal(t, `a\"b\\c\nd`, escapeLabel("a\"b\\c\nd"))
+}
+
+func TestMetrics_EndpointIds(t *testing.T) {
+	m := NewMetrics()
+	ok := func(call *Call) error {
+		call.Status = 200
+		return nil
+	}
+	m.Intercept(&Call{Method: "POST", Url: "https://api.huobi.pro/v1/order/orders/2039498445/submitcancel"}, ok)
+	m.Intercept(&Call{Method: "POST", Url: "https://api.huobi.pro/v1/order/orders/2039498446/submitcancel"}, ok)
+
+	assert.Equal(t, 1, len(m.calls))
+	assert.Equal(t, "https://api.huobi.pro/v1/order/orders/{id}/submitcancel", endpoint("https://api.huobi.pro/v1/order/orders/2039498445/submitcancel"))
+	assert.Equal(t, "http://127.0.0.1:8080/v1/account/accounts/{id}/balance", endpoint("http://127.0.0.1:8080/v1/account/accounts/100009/balance"))
+}
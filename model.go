@@ -1,6 +1,7 @@
 package x
 
 import (
+	json "github.com/buger/jsonparser"
 	"strings"
 )
 
@@ -23,6 +24,7 @@ func ParsePair(pair string) Pair {
 }
 
 type SymbolConfig struct {
+	Pair        Pair
 	AmountScale byte
 	PriceScale  byte
 }
@@ -57,7 +59,7 @@ type Trade struct {
 type TradeType int8
 
 const (
-	All  TradeType = iota - 1
+	All TradeType = iota - 1
 	Sell
 	Buy
 )
@@ -84,6 +86,17 @@ type DepthEntry struct {
 	Amount float64
 }
 
+// ParseDepthEntries reads the [price, amount] pairs of the json array found at keys.
+func ParseDepthEntries(value []byte, keys ...string) []DepthEntry {
+	var entries []DepthEntry
+	json.ArrayEach(value, func(value []byte, dataType json.ValueType, offset int, err error) {
+		price, _ := json.GetFloat(value, "[0]")
+		amount, _ := json.GetFloat(value, "[1]")
+		entries = append(entries, DepthEntry{Price: price, Amount: amount})
+	}, keys...)
+	return entries
+}
+
 type Account struct {
 	Username             string
 	TradePasswordEnabled bool
@@ -119,11 +132,117 @@ type Order struct {
 	Time        uint64
 }
 
+// Fill is a single match of an order.
+type Fill struct {
+	Id        uint64
+	OrderId   uint64
+	Symbol    string
+	TradeType TradeType
+	Price     float64
+	Amount    float64
+	Fee       float64
+	Time      uint64
+}
+
+type OrderRequest struct {
+	Price     float64
+	Amount    float64
+	TradeType TradeType
+}
+
+// OrderResult reports the outcome of one order in a batch operation, Err is nil on success.
+type OrderResult struct {
+	Id  uint64
+	Err error
+}
+
 type OrderStatus uint8
 
 const (
-	Pending         OrderStatus = iota
+	Pending OrderStatus = iota
 	Cancelled
 	Finished
 	PartiallyFilled
 )
+
+type DepositRecord struct {
+	Id            uint64
+	Currency      Currency
+	Address       string
+	Amount        float64
+	Confirmations uint32
+	TxId          string
+	Status        DepositStatus
+	Time          uint64
+}
+
+type DepositStatus uint8
+
+const (
+	DepositConfirming DepositStatus = iota
+	DepositFailed
+	DepositSucceeded
+)
+
+type WithdrawRecord struct {
+	Id       uint64
+	Currency Currency
+	Address  string
+	Amount   float64
+	Fee      float64
+	TxId     string
+	Status   WithdrawStatus
+	Time     uint64
+}
+
+type WithdrawStatus uint8
+
+const (
+	WithdrawSubmitted WithdrawStatus = iota
+	WithdrawFailed
+	WithdrawSucceeded
+	WithdrawCancelled
+	WithdrawProcessing
+)
+
+// MarginAccount is the isolated margin account of one symbol. RiskRate and LiquidationPrice are zero when the
+// exchange does not report them.
+type MarginAccount struct {
+	Id               uint64
+	Symbol           string
+	RiskRate         float64
+	LiquidationPrice float64
+	Assets           []MarginAsset
+}
+
+type MarginAsset struct {
+	Currency  Currency
+	Available float64
+	Freeze    float64
+	Loan      float64
+	Interest  float64
+}
+
+// LoanOrder is a loan taken in a margin account, Balance and InterestBalance are the parts still to be repaid.
+// InterestRate is the daily rate as a fraction, 0.001 for 0.1% a day.
+type LoanOrder struct {
+	Id              uint64
+	Symbol          string
+	Currency        Currency
+	Amount          float64
+	Balance         float64
+	InterestRate    float64
+	Interest        float64
+	InterestBalance float64
+	Status          LoanStatus
+	Time            uint64
+}
+
+type LoanStatus uint8
+
+const (
+	LoanCreated LoanStatus = iota
+	LoanAccrual
+	LoanCleared
+	LoanInvalid
+)
@@ -0,0 +1,16 @@
+package x
+
+import "time"
+
+type Signer interface {
+	Sign(method string, rawUrl string, query Query) error
+}
+
+type Clock func() time.Time
+
+func (c Clock) Now() time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return c()
+}
@@ -0,0 +1,24 @@
+package x
+
+import "strconv"
+
+type ConnState uint8
+
+const (
+	Connecting ConnState = iota
+	Connected
+	Disconnected
+)
+
+var connStateNames = [...]string{
+	Connecting:   "Connecting",
+	Connected:    "Connected",
+	Disconnected: "Disconnected",
+}
+
+func (s ConnState) String() string {
+	if int(s) < len(connStateNames) {
+		return connStateNames[s]
+	}
+	return "ConnState(" + strconv.Itoa(int(s)) + ")"
+}
@@ -0,0 +1,11 @@
+package x
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConnState_String(t *testing.T) {
+	assert.Equal(t, "Connected", Connected.String())
+	assert.Equal(t, "ConnState(9)", ConnState(9).String())
+}
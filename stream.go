@@ -0,0 +1,105 @@
+package x
+
+import "sync"
+
+// OverflowPolicy decides what a Queue does with a value pushed while it is full.
+type OverflowPolicy uint8
+
+const (
+	// Block makes Push wait for room, which also stalls whoever pushes.
+	Block OverflowPolicy = iota
+	// DropOldest discards the oldest queued value to make room.
+	DropOldest
+	// KeepLatest replaces the newest queued value, so a buffer of 1 always holds only the latest value.
+	KeepLatest
+)
+
+type StreamOptions struct {
+	Buffer int
+	Policy OverflowPolicy
+}
+
+// Queue is a bounded FIFO between a producer and a consumer goroutine.
+type Queue struct {
+	size   int
+	policy OverflowPolicy
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	items  []interface{}
+	closed bool
+	done   chan struct{}
+}
+
+// NewQueue creates a queue holding up to options.Buffer values, at least 1.
+func NewQueue(options StreamOptions) *Queue {
+	size := options.Buffer
+	if size < 1 {
+		size = 1
+	}
+
+	q := &Queue{size: size, policy: options.Policy, done: make(chan struct{})}
+	q.cond = sync.NewCond(&q.mutex)
+	return q
+}
+
+// Push adds v according to the policy, values pushed after Close are dropped.
+func (q *Queue) Push(v interface{}) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for q.policy == Block && len(q.items) >= q.size && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return
+	}
+
+	if len(q.items) >= q.size {
+		switch q.policy {
+		case DropOldest:
+			q.items = append(q.items[:0], q.items[1:]...)
+		case KeepLatest:
+			q.items = q.items[:len(q.items)-1]
+		}
+	}
+	q.items = append(q.items, v)
+	q.cond.Broadcast()
+}
+
+// Pop waits for the next value, it returns false once the queue is closed.
+func (q *Queue) Pop() (interface{}, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return nil, false
+	}
+
+	v := q.items[0]
+	q.items[0] = nil
+	q.items = q.items[1:]
+	q.cond.Broadcast()
+	return v, true
+}
+
+// Close drops the queued values and wakes every waiting Push and Pop.
+func (q *Queue) Close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return
+	}
+
+	q.closed = true
+	q.items = nil
+	close(q.done)
+	q.cond.Broadcast()
+}
+
+// Done is closed by Close.
+func (q *Queue) Done() <-chan struct{} {
+	return q.done
+}
@@ -0,0 +1,68 @@
+package x
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func drain(q *Queue, n int) []interface{} {
+	var values []interface{}
+	for i := 0; i < n; i++ {
+		v, _ := q.Pop()
+		values = append(values, v)
+	}
+	return values
+}
+
+func TestQueue_DropOldest(t *testing.T) {
+	q := NewQueue(StreamOptions{Buffer: 2, Policy: DropOldest})
+	for i := 1; i <= 4; i++ {
+		q.Push(i)
+	}
+	assert.Equal(t, []interface{}{3, 4}, drain(q, 2))
+}
+
+func TestQueue_KeepLatest(t *testing.T) {
+	q := NewQueue(StreamOptions{Buffer: 2, Policy: KeepLatest})
+	for i := 1; i <= 4; i++ {
+		q.Push(i)
+	}
+	assert.Equal(t, []interface{}{1, 4}, drain(q, 2))
+
+	q = NewQueue(StreamOptions{Policy: KeepLatest})
+	q.Push(1)
+	q.Push(2)
+	assert.Equal(t, []interface{}{2}, drain(q, 1))
+}
+
+func TestQueue_Block(t *testing.T) {
+	q := NewQueue(StreamOptions{Buffer: 1, Policy: Block})
+	q.Push(1)
+
+	pushed := make(chan struct{})
+	go func() {
+		q.Push(2)
+		close(pushed)
+	}()
+	select {
+	case <-pushed:
+		t.Fatal("push did not block on a full queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	assert.Equal(t, []interface{}{1, 2}, drain(q, 2))
+	<-pushed
+}
+
+func TestQueue_Close(t *testing.T) {
+	q := NewQueue(StreamOptions{Buffer: 1, Policy: Block})
+	q.Push(1)
+	go q.Close()
+
+	// a blocked push returns once the queue is closed
+	q.Push(2)
+	_, ok := q.Pop()
+	assert.False(t, ok)
+	<-q.Done()
+}
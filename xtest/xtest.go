@@ -0,0 +1,53 @@
+// Package xtest holds the fake servers shared by the tests of the exchange clients.
+package xtest
+
+import (
+	encoding "encoding/json"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// RewriteTransport sends every request to Target, whichever host it was made for.
+type RewriteTransport struct {
+	Target *url.URL
+}
+
+func (t RewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r.URL.Scheme, r.URL.Host = t.Target.Scheme, t.Target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// NewHttpServer starts a server running handler and returns a client which sends every request to it.
+func NewHttpServer(handler http.HandlerFunc) (*http.Client, func()) {
+	server := httptest.NewServer(handler)
+	target, _ := url.Parse(server.URL)
+	return &http.Client{Transport: RewriteTransport{Target: target}}, server.Close
+}
+
+// NewWebSocketServer starts a server which hands every decoded client message to handler and returns its url.
+func NewWebSocketServer(t *testing.T, handler func(conn *websocket.Conn, message map[string]interface{})) (string, func()) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var message map[string]interface{}
+			encoding.Unmarshal(data, &message)
+			handler(conn, message)
+		}
+	}))
+	return "ws" + strings.TrimPrefix(server.URL, "http"), server.Close
+}
@@ -8,13 +8,21 @@ import (
 
 func marshalTicker(value []byte) Ticker {
 	ticker, _, _, _ := json.Get(value, "ticker")
+	timeString, _ := json.GetString(value, "date")
+	time, _ := strconv.ParseUint(timeString, 10, 64)
+
+	t := marshalTickerFields(ticker)
+	t.Time = time
+	return t
+}
+
+func marshalTickerFields(ticker []byte) Ticker {
 	amountString, _ := json.GetString(ticker, "vol")
 	lastString, _ := json.GetString(ticker, "last")
 	sellString, _ := json.GetString(ticker, "sell")
 	buyString, _ := json.GetString(ticker, "buy")
 	highString, _ := json.GetString(ticker, "high")
 	lowString, _ := json.GetString(ticker, "low")
-	timeString, _ := json.GetString(value, "date")
 
 	amount, _ := strconv.ParseFloat(amountString, 64)
 	last, _ := strconv.ParseFloat(lastString, 64)
@@ -22,21 +30,57 @@ func marshalTicker(value []byte) Ticker {
 	buy, _ := strconv.ParseFloat(buyString, 64)
 	high, _ := strconv.ParseFloat(highString, 64)
 	low, _ := strconv.ParseFloat(lowString, 64)
-	time, _ := strconv.ParseUint(timeString, 10, 64)
 
-	return Ticker{Amount: amount, Last: last, Ask: sell, Bid: buy, High: high, Low: low, Time: time}
+	return Ticker{Amount: amount, Last: last, Ask: sell, Bid: buy, High: high, Low: low}
 }
 
-func marshalDepthEntries(value []byte, keys ...string) []DepthEntry {
-	var entry []DepthEntry
+func marshalDepth(value []byte) Depth {
+	time, _ := json.GetInt(value, "timestamp")
+	asks, bids := ParseDepthEntries(value, "asks"), ParseDepthEntries(value, "bids")
+	return Depth{Asks: asks, Bids: bids, Time: uint64(time)}
+}
+
+func marshalTrades(value []byte, keys ...string) []Trade {
+	var trades []Trade
 	json.ArrayEach(value, func(value []byte, dataType json.ValueType, offset int, err error) {
-		price, _ := json.GetFloat(value, "[0]")
-		amount, _ := json.GetFloat(value, "[1]")
-		entry = append(entry, DepthEntry{Price: price, Amount: amount})
+		id, _ := json.GetInt(value, "tid")
+		tradeType, _ := json.GetString(value, "type")
+		amountString, _ := json.GetString(value, "amount")
+		priceString, _ := json.GetString(value, "price")
+		time, _ := json.GetInt(value, "date")
+
+		amount, _ := strconv.ParseFloat(amountString, 64)
+		price, _ := strconv.ParseFloat(priceString, 64)
+
+		trades = append(trades, Trade{Id: uint64(id), TradeType: ParseTradeType(tradeType), Price: price, Amount: amount, Time: uint64(time)})
+	}, keys...)
+	return trades
+}
+
+func marshalKlines(value []byte, keys ...string) []Kline {
+	var klines []Kline
+	json.ArrayEach(value, func(value []byte, dataType json.ValueType, offset int, err error) {
+		time, _ := json.GetInt(value, "[0]")
+		open, _ := json.GetFloat(value, "[1]")
+		high, _ := json.GetFloat(value, "[2]")
+		low, _ := json.GetFloat(value, "[3]")
+		close, _ := json.GetFloat(value, "[4]")
+		amount, _ := json.GetFloat(value, "[5]")
+		klines = append(klines, Kline{Time: uint64(time), Open: open, High: high, Low: low, Close: close, Amount: amount})
 	}, keys...)
-	return entry
+	return klines
 }
 
 func parseSymbol(pair Pair) string {
 	return pair.Base.Symbol + "_" + pair.Valuation.Symbol
-}
\ No newline at end of file
+}
+
+func getFloat(value []byte, keys ...string) float64 {
+	if f, err := json.GetFloat(value, keys...); err == nil {
+		return f
+	}
+
+	s, _ := json.GetString(value, keys...)
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
@@ -0,0 +1,143 @@
+package zb
+
+import (
+	. "github.com/berryland/x"
+	json "github.com/buger/jsonparser"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var withdrawStatuses = map[int64]WithdrawStatus{
+	0: WithdrawSubmitted,
+	1: WithdrawFailed,
+	2: WithdrawSucceeded,
+	3: WithdrawCancelled,
+	5: WithdrawSucceeded,
+}
+
+var depositStatuses = map[int64]DepositStatus{
+	0: DepositConfirming,
+	1: DepositFailed,
+	2: DepositSucceeded,
+}
+
+var chinaStandardTime = time.FixedZone("CST", 8*60*60)
+
+func (c *ZbHttpClient) GetDepositAddress(currency string, accessKey, secretKey string) (string, error) {
+	bytes, err := c.getTrade("getUserAddress", Query{"currency": currency}, accessKey, secretKey)
+	if err != nil {
+		return "", err
+	}
+
+	address, _ := json.GetString(bytes, "message", "datas", "key")
+	return address, nil
+}
+
+func (c *ZbHttpClient) GetWithdrawAddress(currency string, accessKey, secretKey string) (string, error) {
+	bytes, err := c.getTrade("getWithdrawAddress", Query{"currency": currency}, accessKey, secretKey)
+	if err != nil {
+		return "", err
+	}
+
+	address, _ := json.GetString(bytes, "message", "datas", "key")
+	return address, nil
+}
+
+// Withdraw submits a withdrawal to address and returns its id, internal allows the transfer to be settled inside ZB.
+func (c *ZbHttpClient) Withdraw(currency string, address string, amount, fee float64, internal bool, safePwd string, accessKey, secretKey string) (uint64, error) {
+	q := Query{
+		"currency":    currency,
+		"receiveAddr": address,
+		"amount":      amount,
+		"fees":        fee,
+		"itransfer":   0,
+		"safePwd":     safePwd,
+	}
+	if internal {
+		q["itransfer"] = 1
+	}
+
+	bytes, err := c.getTrade("withdraw", q, accessKey, secretKey)
+	if err != nil {
+		return 0, err
+	}
+
+	idString, _ := json.GetString(bytes, "id")
+	id, _ := strconv.ParseUint(idString, 10, 64)
+	return id, nil
+}
+
+func (c *ZbHttpClient) CancelWithdraw(currency string, id uint64, safePwd string, accessKey, secretKey string) error {
+	q := Query{
+		"currency":   currency,
+		"downloadId": id,
+		"safePwd":    safePwd,
+	}
+
+	_, err := c.getTrade("cancelWithdraw", q, accessKey, secretKey)
+	return err
+}
+
+func (c *ZbHttpClient) GetWithdrawRecords(currency string, page uint64, size uint16, accessKey, secretKey string) ([]WithdrawRecord, error) {
+	q := Query{
+		"currency":  currency,
+		"pageIndex": page,
+		"pageSize":  size,
+	}
+	bytes, err := c.getTrade("getWithdrawRecord", q, accessKey, secretKey)
+	if err != nil {
+		return []WithdrawRecord{}, err
+	}
+
+	var records []WithdrawRecord
+	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+		id, _ := json.GetInt(value, "id")
+		address, _ := json.GetString(value, "toAddress")
+		amount := getFloat(value, "amount")
+		fee := getFloat(value, "fees")
+		status, _ := json.GetInt(value, "status")
+		submitTime, _ := json.GetInt(value, "submitTime")
+		records = append(records, WithdrawRecord{Id: uint64(id), Currency: ParseCurrency(currency), Address: address, Amount: amount, Fee: fee, Status: withdrawStatuses[status], Time: uint64(submitTime)})
+	}, "message", "datas", "list")
+
+	return records, nil
+}
+
+func (c *ZbHttpClient) GetDepositRecords(currency string, page uint64, size uint16, accessKey, secretKey string) ([]DepositRecord, error) {
+	q := Query{
+		"currency":  currency,
+		"pageIndex": page,
+		"pageSize":  size,
+	}
+	bytes, err := c.getTrade("getChargeRecord", q, accessKey, secretKey)
+	if err != nil {
+		return []DepositRecord{}, err
+	}
+
+	var records []DepositRecord
+	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+		id, _ := json.GetInt(value, "id")
+		address, _ := json.GetString(value, "address")
+		amount := getFloat(value, "amount")
+		confirmations, _ := json.GetInt(value, "confirmTimes")
+		hash, _ := json.GetString(value, "hash")
+		status, _ := json.GetInt(value, "status")
+		records = append(records, DepositRecord{Id: uint64(id), Currency: ParseCurrency(currency), Address: address, Amount: amount, Confirmations: uint32(confirmations), TxId: hash, Status: depositStatuses[status], Time: getChargeTime(value)})
+	}, "message", "datas", "list")
+
+	return records, nil
+}
+
+func getChargeTime(value []byte) uint64 {
+	if submitTime, err := json.GetInt(value, "submitTime"); err == nil {
+		return uint64(submitTime)
+	}
+
+	submitTime, _ := json.GetString(value, "submit_time")
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", strings.TrimSpace(submitTime), chinaStandardTime)
+	if err != nil {
+		return 0
+	}
+	return uint64(t.UnixNano() / int64(time.Millisecond))
+}
@@ -0,0 +1,76 @@
+package zb
+
+import (
+	. "github.com/berryland/x"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestZbHttpClient_GetDepositAddress(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/getUserAddress", r.URL.Path)
+		assert.Equal(t, "getUserAddress", r.URL.Query().Get("method"))
+		assert.Equal(t, "eth", r.URL.Query().Get("currency"))
+		assert.NotEmpty(t, r.URL.Query().Get("sign"))
+		w.Write([]byte(`{"code":1000,"message":{"des":"success","isSuc":true,"datas":{"key":"0x6b2a2c1e"}}}`))
+	})
+	defer shutdown()
+
+	address, err := c.GetDepositAddress("eth", accessKey, secretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "0x6b2a2c1e", address)
+}
+
+func TestZbHttpClient_Withdraw(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assert.Equal(t, "withdraw", q.Get("method"))
+		assert.Equal(t, "0.5", q.Get("amount"))
+		assert.Equal(t, "0.001", q.Get("fees"))
+		assert.Equal(t, "1", q.Get("itransfer"))
+		assert.Equal(t, "1MyAddress", q.Get("receiveAddr"))
+		assert.Equal(t, "123456", q.Get("safePwd"))
+		w.Write([]byte(`{"code":1000,"message":"success","id":"2016042556231"}`))
+	})
+	defer shutdown()
+
+	id, err := c.Withdraw("btc", "1MyAddress", 0.5, 0.001, true, "123456", accessKey, secretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2016042556231), id)
+}
+
+func TestZbHttpClient_CancelWithdraw(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "2016042556231", r.URL.Query().Get("downloadId"))
+		w.Write([]byte(`{"code":1001,"message":"cannot cancel"}`))
+	})
+	defer shutdown()
+
+	err := c.CancelWithdraw("btc", 2016042556231, "123456", accessKey, secretKey)
+	assert.Equal(t, GeneralError, err.(*ApiError).Code)
+}
+
+func TestZbHttpClient_GetWithdrawRecords(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "getWithdrawRecord", r.URL.Query().Get("method"))
+		w.Write([]byte(`{"code":1000,"message":{"des":"success","isSuc":true,"datas":{"list":[{"amount":0.01,"fees":0.001,"id":2016042556231,"manageTime":1461579340000,"status":3,"submitTime":1461579288000,"toAddress":"14fxEPirL9fyfw1i9EF439Pq6gQ5xijUmp"}],"pageIndex":1,"pageSize":10,"totalCount":1,"totalPage":1}}}`))
+	})
+	defer shutdown()
+
+	records, err := c.GetWithdrawRecords("btc", 1, 10, accessKey, secretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []WithdrawRecord{{Id: 2016042556231, Currency: ParseCurrency("btc"), Address: "14fxEPirL9fyfw1i9EF439Pq6gQ5xijUmp", Amount: 0.01, Fee: 0.001, Status: WithdrawCancelled, Time: 1461579288000}}, records)
+}
+
+func TestZbHttpClient_GetDepositRecords(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "getChargeRecord", r.URL.Query().Get("method"))
+		w.Write([]byte(`{"code":1000,"message":{"des":"success","isSuc":true,"datas":{"list":[{"address":"1FKN1DZqCm8HaTujDioRL2Aezdh7Qj7xxx","amount":"1.00000000","confirmTimes":1,"currency":"BTC","description":"confirmed","hash":"7ce842de187c379abafadd64a5fe66c5c61c8a21fb04edff9532234a1dae6xxx","id":558,"itransfer":1,"status":2,"submit_time":"2016-12-07 18:51:57"}],"pageIndex":1,"pageSize":10,"total":8}}}`))
+	})
+	defer shutdown()
+
+	records, err := c.GetDepositRecords("btc", 1, 10, accessKey, secretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []DepositRecord{{Id: 558, Currency: ParseCurrency("btc"), Address: "1FKN1DZqCm8HaTujDioRL2Aezdh7Qj7xxx", Amount: 1, Confirmations: 1, TxId: "7ce842de187c379abafadd64a5fe66c5c61c8a21fb04edff9532234a1dae6xxx", Status: DepositSucceeded, Time: 1481107917000}}, records)
+}
@@ -1,18 +1,13 @@
 package zb
 
 import (
-	"crypto/hmac"
-	"crypto/md5"
-	"crypto/sha1"
-	"fmt"
+	"errors"
 	. "github.com/berryland/x"
 	json "github.com/buger/jsonparser"
-	"net/url"
-	"sort"
+	"net/http"
 	"strconv"
 	"strings"
 	"time"
-	"net/http"
 )
 
 const (
@@ -53,21 +48,41 @@ var ApiCodes = map[uint16]ApiCode{
 
 type ZbHttpClient struct {
 	Client *HttpClient
+	Cache  *Cache
+	group  Group
 }
 
+var (
+	_ HttpApiClient  = (*ZbHttpClient)(nil)
+	_ TradeApiClient = (*ZbHttpClient)(nil)
+)
+
 func NewHttpClient() *ZbHttpClient {
 	return &ZbHttpClient{Client: &HttpClient{Client: &http.Client{}}}
 }
 
 func (c *ZbHttpClient) GetSymbols() (map[string]SymbolConfig, error) {
-	configs := map[string]SymbolConfig{}
-	resp, err := c.Client.DoGet(DataApiUrl+"markets", Query{})
+	if c.Cache == nil {
+		return c.getSymbols()
+	}
+
+	value, err := c.Cache.Get("markets", func() (interface{}, error) {
+		return c.getSymbols()
+	})
 	if err != nil {
-		return configs, err
+		return map[string]SymbolConfig{}, err
+	}
+
+	configs := map[string]SymbolConfig{}
+	for k, v := range value.(map[string]SymbolConfig) {
+		configs[k] = v
 	}
+	return configs, nil
+}
 
-	bytes := resp.ReadBytes()
-	err = extractDataApiError(bytes)
+func (c *ZbHttpClient) getSymbols() (map[string]SymbolConfig, error) {
+	configs := map[string]SymbolConfig{}
+	bytes, err := c.getData("markets", Query{})
 	if err != nil {
 		return configs, err
 	}
@@ -76,28 +91,60 @@ func (c *ZbHttpClient) GetSymbols() (map[string]SymbolConfig, error) {
 		symbol, _ := json.ParseString(key)
 		amountScale, _ := json.GetInt(value, "amountScale")
 		priceScale, _ := json.GetInt(value, "priceScale")
-		configs[symbol] = SymbolConfig{AmountScale: byte(amountScale), PriceScale: byte(priceScale)}
+		configs[symbol] = SymbolConfig{Pair: ParsePair(symbol), AmountScale: byte(amountScale), PriceScale: byte(priceScale)}
 		return nil
 	})
 	return configs, nil
 }
 
 func (c *ZbHttpClient) GetTicker(pair Pair) (Ticker, error) {
+	value, err := c.group.Do("ticker?"+parseSymbol(pair), func() (interface{}, error) {
+		return c.getTicker(pair)
+	})
+	ticker, _ := value.(Ticker)
+	return ticker, err
+}
+
+func (c *ZbHttpClient) getTicker(pair Pair) (Ticker, error) {
 	q := Query{
 		"market": parseSymbol(pair),
 	}
-	resp, err := c.Client.DoGet(DataApiUrl+"ticker", q)
+	bytes, err := c.getData("ticker", q)
 	if err != nil {
 		return Ticker{}, err
 	}
 
-	bytes := resp.ReadBytes()
-	err = extractDataApiError(bytes)
+	return marshalTicker(bytes), nil
+}
+
+// GetAllTickers returns the tickers of every market. allTicker carries no timestamp, so the local time is used instead.
+func (c *ZbHttpClient) GetAllTickers() (map[Pair]Ticker, error) {
+	tickers := map[Pair]Ticker{}
+	symbols, err := c.GetSymbols()
+	if err != nil {
+		return tickers, err
+	}
+
+	bytes, err := c.getData("allTicker", Query{})
 	if err != nil {
-		return Ticker{}, err
+		return tickers, err
 	}
 
-	return marshalTicker(bytes), nil
+	pairs := map[string]Pair{}
+	for symbol, config := range symbols {
+		pairs[strings.Replace(symbol, "_", "", 1)] = config.Pair
+	}
+
+	now := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	json.ObjectEach(bytes, func(key []byte, value []byte, dataType json.ValueType, offset int) error {
+		if pair, ok := pairs[string(key)]; ok {
+			ticker := marshalTickerFields(value)
+			ticker.Time = now
+			tickers[pair] = ticker
+		}
+		return nil
+	})
+	return tickers, nil
 }
 
 func (c *ZbHttpClient) GetKlines(pair Pair, period string, since uint64, size uint16) ([]Kline, error) {
@@ -108,28 +155,12 @@ func (c *ZbHttpClient) GetKlines(pair Pair, period string, since uint64, size ui
 		"since":  since,
 		"size":   size,
 	}
-	resp, err := c.Client.DoGet(DataApiUrl+"kline", q)
-	if err != nil {
-		return klines, err
-	}
-
-	bytes := resp.ReadBytes()
-	err = extractDataApiError(bytes)
+	bytes, err := c.getData("kline", q)
 	if err != nil {
 		return klines, err
 	}
 
-	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
-		time, _ := json.GetInt(value, "[0]")
-		open, _ := json.GetFloat(value, "[1]")
-		high, _ := json.GetFloat(value, "[2]")
-		low, _ := json.GetFloat(value, "[3]")
-		close, _ := json.GetFloat(value, "[4]")
-		amount, _ := json.GetFloat(value, "[5]")
-		klines = append(klines, Kline{Time: uint64(time), Open: open, High: high, Low: low, Close: close, Amount: amount})
-	}, "data")
-
-	return klines, nil
+	return marshalKlines(bytes, "data"), nil
 }
 
 func (c *ZbHttpClient) GetTrades(symbol string, since uint64) ([]Trade, error) {
@@ -138,96 +169,43 @@ func (c *ZbHttpClient) GetTrades(symbol string, since uint64) ([]Trade, error) {
 		"market": symbol,
 		"since":  since,
 	}
-	resp, err := c.Client.DoGet(DataApiUrl+"trades", q)
-	if err != nil {
-		return trades, err
-	}
-
-	bytes := resp.ReadBytes()
-	err = extractDataApiError(bytes)
+	bytes, err := c.getData("trades", q)
 	if err != nil {
 		return trades, err
 	}
 
-	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
-		id, _ := json.GetInt(value, "tid")
-		tradeType, _ := json.GetString(value, "type")
-		amountString, _ := json.GetString(value, "amount")
-		priceString, _ := json.GetString(value, "price")
-		time, _ := json.GetInt(value, "date")
-
-		amount, _ := strconv.ParseFloat(amountString, 64)
-		price, _ := strconv.ParseFloat(priceString, 64)
+	return marshalTrades(bytes), nil
+}
 
-		trades = append(trades, Trade{Id: uint64(id), TradeType: ParseTradeType(tradeType), Price: price, Amount: amount, Time: uint64(time)})
+func (c *ZbHttpClient) GetDepth(symbol string, size uint8) (Depth, error) {
+	value, err := c.group.Do("depth?"+symbol+"&"+strconv.Itoa(int(size)), func() (interface{}, error) {
+		return c.getDepth(symbol, size)
 	})
-
-	return trades, nil
+	depth, _ := value.(Depth)
+	return Depth{Asks: append([]DepthEntry(nil), depth.Asks...), Bids: append([]DepthEntry(nil), depth.Bids...), Time: depth.Time}, err
 }
 
-func (c *ZbHttpClient) GetDepth(symbol string, size uint8) (Depth, error) {
+func (c *ZbHttpClient) getDepth(symbol string, size uint8) (Depth, error) {
 	q := Query{
 		"market": symbol,
 		"size":   size,
 	}
-	resp, err := c.Client.DoGet(DataApiUrl+"depth", q)
-	if err != nil {
-		return Depth{}, err
-	}
-
-	bytes := resp.ReadBytes()
-	err = extractDataApiError(bytes)
+	bytes, err := c.getData("depth", q)
 	if err != nil {
 		return Depth{}, err
 	}
 
-	time, _ := json.GetInt(bytes, "timestamp")
-	asks, bids := marshalDepthEntries(bytes, "asks"), marshalDepthEntries(bytes, "bids")
-
-	return Depth{Asks: asks, Bids: bids, Time: uint64(time)}, nil
+	return marshalDepth(bytes), nil
 }
 
 func (c *ZbHttpClient) GetAccount(accessKey string, secretKey string) (Account, error) {
-	q := Query{
-		"accesskey": accessKey,
-		"method":    "getAccountInfo",
-	}.Encode()
-
-	sign(q, secretKey)
-
-	resp, err := c.Client.DoGet(TradeApiUrl+"getAccountInfo", q)
-	if err != nil {
-		return Account{}, err
-	}
-
-	bytes := resp.ReadBytes()
-	err = extractTradeApiError(bytes)
+	bytes, err := c.getTrade("getAccountInfo", Query{}, accessKey, secretKey)
 	if err != nil {
 		return Account{}, err
 	}
 
-	var assets []Asset
 	result, _, _, _ := json.Get(bytes, "result")
-	json.ArrayEach(result, func(value []byte, dataType json.ValueType, offset int, err error) {
-		freezeString, _ := json.GetString(value, "freez")
-		freeze, _ := strconv.ParseFloat(freezeString, 64)
-		availableString, _ := json.GetString(value, "available")
-		available, _ := strconv.ParseFloat(availableString, 64)
-		coinCnName, _ := json.GetString(value, "cnName")
-		coinEnName, _ := json.GetString(value, "enName")
-		coinKey, _ := json.GetString(value, "key")
-		coinUnit, _ := json.GetString(value, "unitTag")
-		coinScale, _ := json.GetInt(value, "unitDecimal")
-		assets = append(assets, Asset{Freeze: freeze, Available: available, Coin: Coin{CnName: coinCnName, EnName: coinEnName, Key: coinKey, Unit: coinUnit, Scale: uint8(coinScale)}})
-	}, "coins")
-
-	base, _, _, _ := json.Get(result, "base")
-	username, _ := json.GetString(base, "username")
-	tradePasswordEnabled, _ := json.GetBoolean(base, "trade_password_enabled")
-	authGoogleEnabled, _ := json.GetBoolean(base, "auth_google_enabled")
-	authMobileEnabled, _ := json.GetBoolean(base, "auth_mobile_enabled")
-
-	return Account{Username: username, TradePasswordEnabled: tradePasswordEnabled, AuthGoogleEnabled: authGoogleEnabled, AuthMobileEnabled: authMobileEnabled, Assets: assets}, nil
+	return marshalAccount(result), nil
 }
 
 func (c *ZbHttpClient) PlaceOrder(symbol string, price, amount float64, tradeType TradeType, accessKey, secretKey string) (uint64, error) {
@@ -236,19 +214,8 @@ func (c *ZbHttpClient) PlaceOrder(symbol string, price, amount float64, tradeTyp
 		"price":     price,
 		"amount":    amount,
 		"tradeType": tradeType,
-		"accesskey": accessKey,
-		"method":    "order",
-	}.Encode()
-
-	sign(q, secretKey)
-
-	resp, err := c.Client.DoGet(TradeApiUrl+"order", q)
-	if err != nil {
-		return 0, err
 	}
-
-	bytes := resp.ReadBytes()
-	err = extractTradeApiError(bytes)
+	bytes, err := c.getTrade("order", q, accessKey, secretKey)
 	if err != nil {
 		return 0, err
 	}
@@ -260,45 +227,20 @@ func (c *ZbHttpClient) PlaceOrder(symbol string, price, amount float64, tradeTyp
 
 func (c *ZbHttpClient) CancelOrder(symbol string, id uint64, accessKey, secretKey string) error {
 	q := Query{
-		"currency":  symbol,
-		"id":        id,
-		"accesskey": accessKey,
-		"method":    "cancelOrder",
-	}.Encode()
-
-	sign(q, secretKey)
-
-	resp, err := c.Client.DoGet(TradeApiUrl+"cancelOrder", q)
-	if err != nil {
-		return err
+		"currency": symbol,
+		"id":       id,
 	}
 
-	bytes := resp.ReadBytes()
-	err = extractTradeApiError(bytes)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	_, err := c.getTrade("cancelOrder", q, accessKey, secretKey)
+	return err
 }
 
 func (c *ZbHttpClient) GetOrder(symbol string, id uint64, accessKey, secretKey string) (Order, error) {
 	q := Query{
-		"currency":  symbol,
-		"id":        strconv.FormatUint(id, 10),
-		"accesskey": accessKey,
-		"method":    "getOrder",
-	}.Encode()
-
-	sign(q, secretKey)
-
-	resp, err := c.Client.DoGet(TradeApiUrl+"getOrder", q)
-	if err != nil {
-		return Order{}, err
+		"currency": symbol,
+		"id":       strconv.FormatUint(id, 10),
 	}
-
-	bytes := resp.ReadBytes()
-	err = extractTradeApiError(bytes)
+	bytes, err := c.getTrade("getOrder", q, accessKey, secretKey)
 	if err != nil {
 		return Order{}, err
 	}
@@ -307,14 +249,11 @@ func (c *ZbHttpClient) GetOrder(symbol string, id uint64, accessKey, secretKey s
 }
 
 func (c *ZbHttpClient) GetOrders(symbol string, tradeType TradeType, page uint64, size uint16, accessKey, secretKey string) ([]Order, error) {
-	u := getUrlToGetOrders(symbol, tradeType, page, size, accessKey, secretKey)
-	resp, err := c.Client.DoGet(u.String(), nil)
+	method, q, err := getOrdersQuery(symbol, tradeType, page, size)
 	if err != nil {
 		return []Order{}, err
 	}
-
-	bytes := resp.ReadBytes()
-	err = extractTradeApiError(bytes)
+	bytes, err := c.getTrade(method, q, accessKey, secretKey)
 	if err != nil {
 		return []Order{}, err
 	}
@@ -327,6 +266,88 @@ func (c *ZbHttpClient) GetOrders(symbol string, tradeType TradeType, page uint64
 	return orders, nil
 }
 
+// GetOpenOrders returns every unfinished order of symbol, walking all pages.
+func (c *ZbHttpClient) GetOpenOrders(symbol string, accessKey, secretKey string) ([]Order, error) {
+	const size = 10
+	var orders []Order
+	for page := uint64(1); ; page++ {
+		q := Query{
+			"currency":  symbol,
+			"pageIndex": page,
+			"pageSize":  size,
+		}
+		bytes, err := c.getTrade("getUnfinishedOrdersIgnoreTradeType", q, accessKey, secretKey)
+		if e, ok := err.(*ApiError); ok && e.Code == OrderNotFound {
+			return orders, nil
+		}
+		if err != nil {
+			return orders, err
+		}
+
+		count := 0
+		json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+			orders = append(orders, parseOrder(value))
+			count++
+		})
+		if count < size {
+			return orders, nil
+		}
+	}
+}
+
+func (c *ZbHttpClient) PlaceOrders(symbol string, requests []OrderRequest, accessKey, secretKey string) []OrderResult {
+	results := make([]OrderResult, len(requests))
+	for i, r := range requests {
+		results[i].Id, results[i].Err = c.PlaceOrder(symbol, r.Price, r.Amount, r.TradeType, accessKey, secretKey)
+	}
+	return results
+}
+
+func (c *ZbHttpClient) CancelOrders(symbol string, ids []uint64, accessKey, secretKey string) []OrderResult {
+	results := make([]OrderResult, len(ids))
+	for i, id := range ids {
+		results[i] = OrderResult{Id: id, Err: c.CancelOrder(symbol, id, accessKey, secretKey)}
+	}
+	return results
+}
+
+func (c *ZbHttpClient) CancelAllOrders(symbol string, accessKey, secretKey string) ([]OrderResult, error) {
+	orders, err := c.GetOpenOrders(symbol, accessKey, secretKey)
+	if err != nil {
+		return []OrderResult{}, err
+	}
+
+	ids := make([]uint64, len(orders))
+	for i, order := range orders {
+		ids[i] = order.Id
+	}
+	return c.CancelOrders(symbol, ids, accessKey, secretKey), nil
+}
+
+func marshalAccount(value []byte) Account {
+	var assets []Asset
+	json.ArrayEach(value, func(value []byte, dataType json.ValueType, offset int, err error) {
+		freezeString, _ := json.GetString(value, "freez")
+		freeze, _ := strconv.ParseFloat(freezeString, 64)
+		availableString, _ := json.GetString(value, "available")
+		available, _ := strconv.ParseFloat(availableString, 64)
+		coinCnName, _ := json.GetString(value, "cnName")
+		coinEnName, _ := json.GetString(value, "enName")
+		coinKey, _ := json.GetString(value, "key")
+		coinUnit, _ := json.GetString(value, "unitTag")
+		coinScale, _ := json.GetInt(value, "unitDecimal")
+		assets = append(assets, Asset{Freeze: freeze, Available: available, Coin: Coin{CnName: coinCnName, EnName: coinEnName, Key: coinKey, Unit: coinUnit, Scale: uint8(coinScale)}})
+	}, "coins")
+
+	base, _, _, _ := json.Get(value, "base")
+	username, _ := json.GetString(base, "username")
+	tradePasswordEnabled, _ := json.GetBoolean(base, "trade_password_enabled")
+	authGoogleEnabled, _ := json.GetBoolean(base, "auth_google_enabled")
+	authMobileEnabled, _ := json.GetBoolean(base, "auth_mobile_enabled")
+
+	return Account{Username: username, TradePasswordEnabled: tradePasswordEnabled, AuthGoogleEnabled: authGoogleEnabled, AuthMobileEnabled: authMobileEnabled, Assets: assets}
+}
+
 func parseOrder(value []byte) Order {
 	idString, _ := json.GetString(value, "id")
 	id, _ := strconv.ParseUint(idString, 10, 64)
@@ -342,72 +363,34 @@ func parseOrder(value []byte) Order {
 	return Order{Id: id, Price: price, Average: tradePrice, TotalAmount: totalAmount, TradeAmount: tradeAmount, TradeMoney: tradeMoney, Symbol: currency, Status: OrderStatus(status), TradeType: TradeType(tradeType), Time: uint64(tradeDate)}
 }
 
-func getUrlToGetOrders(symbol string, tradeType TradeType, page uint64, size uint16, accessKey, secretKey string) *url.URL {
-	switch tradeType {
-	case All:
-		return getOrdersIgnoreTradeType(symbol, page, size, accessKey, secretKey)
-	case Buy, Sell:
-		return getOrdersNew(symbol, tradeType, page, size, accessKey, secretKey)
-	default:
-		panic("Unknown trade type: " + string(tradeType))
-	}
-}
-
-func getOrdersIgnoreTradeType(symbol string, page uint64, size uint16, accessKey, secretKey string) *url.URL {
+func getOrdersQuery(symbol string, tradeType TradeType, page uint64, size uint16) (string, Query, error) {
 	q := Query{
 		"currency":  symbol,
 		"pageIndex": page,
 		"pageSize":  size,
-		"accesskey": accessKey,
-		"method":    "getOrdersIgnoreTradeType",
-	}.Encode()
-
-	sign(q, secretKey)
-
-	u := BuildUrl(TradeApiUrl+"getOrdersIgnoreTradeType", q)
-	return u
-}
-
-func getOrdersNew(symbol string, tradeType TradeType, page uint64, size uint16, accessKey, secretKey string) *url.URL {
-	q := Query{
-		"currency":  symbol,
-		"tradeType": tradeType,
-		"pageIndex": page,
-		"pageSize":  size,
-		"accesskey": accessKey,
-		"method":    "getOrdersNew",
-	}.Encode()
-
-	sign(q, secretKey)
-
-	u := BuildUrl(TradeApiUrl+"getOrdersNew", q)
-	return u
-}
+	}
 
-func sign(query Query, secretKey string) {
-	query["sign"] = genSign(secretKey, query)
-	query["reqTime"] = time.Now().Unix() * 1000
+	switch tradeType {
+	case All:
+		return "getOrdersIgnoreTradeType", q, nil
+	case Buy, Sell:
+		q["tradeType"] = tradeType
+		return "getOrdersNew", q, nil
+	default:
+		return "", nil, errors.New("Unsupported trade type: " + strconv.Itoa(int(tradeType)))
+	}
 }
 
-func genSign(secretKey string, params map[string]interface{}) string {
-	h := hmac.New(md5.New, []byte(fmt.Sprintf("%x", sha1.Sum([]byte(secretKey)))))
-	h.Write([]byte(getSortedQueryString(params)))
-	return fmt.Sprintf("%x", h.Sum(nil))
+func (c *ZbHttpClient) getData(path string, query Query) ([]byte, error) {
+	return c.Client.Invoke(http.MethodGet, DataApiUrl+path, query, nil, extractDataApiError)
 }
 
-func getSortedQueryString(params map[string]interface{}) string {
-	keys := make([]string, len(params))
-	for k := range params {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	var kvs []string
-	for _, k := range keys {
-		kvs = append(kvs, fmt.Sprintf("%v=%v", k, params[k]))
+func (c *ZbHttpClient) getTrade(method string, query Query, accessKey, secretKey string) ([]byte, error) {
+	query["method"] = method
+	if err := NewSigner(accessKey, secretKey).Sign(http.MethodGet, TradeApiUrl+method, query); err != nil {
+		return nil, err
 	}
-
-	return strings.Join(kvs, "&")
+	return c.Client.Invoke(http.MethodGet, TradeApiUrl+method, query, nil, extractTradeApiError)
 }
 
 func extractDataApiError(value []byte) error {
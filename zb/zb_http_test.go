@@ -2,9 +2,15 @@ package zb
 
 import (
 	. "github.com/berryland/x"
+	"github.com/berryland/x/xtest"
 	"github.com/stretchr/testify/assert"
+	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 var (
@@ -65,3 +71,153 @@ func TestZbHttpClient_PlaceOrder(t *testing.T) {
 func TestZbHttpClient_CancelOrder(t *testing.T) {
 	NewHttpClient().CancelOrder("btc_usdt", 2018012261281063, accessKey, secretKey)
 }
+
+func newTestHttpClient(handler http.HandlerFunc) (*ZbHttpClient, func()) {
+	c := NewHttpClient()
+	client, shutdown := xtest.NewHttpServer(handler)
+	c.Client.Client = client
+	return c, shutdown
+}
+
+func TestZbHttpClient_GetTickerCoalesced(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		w.Write([]byte(`{"date":"1516536000000","ticker":{"vol":"10","last":"11000","sell":"11001","buy":"10999","high":"12000","low":"10000"}}`))
+	})
+	defer shutdown()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker, err := c.GetTicker(ParsePair("btc_usdt"))
+			assert.Nil(t, err)
+			assert.Equal(t, 11000.0, ticker.Last)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), hits)
+}
+
+func TestZbHttpClient_GetSymbolsCached(t *testing.T) {
+	var hits int32
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/data/v1/markets", r.URL.Path)
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(`{"btc_usdt":{"amountScale":4,"priceScale":2}}`))
+	})
+	defer shutdown()
+	c.Cache = NewCache(time.Minute, 0)
+
+	for i := 0; i < 3; i++ {
+		symbols, err := c.GetSymbols()
+		assert.Nil(t, err)
+		assert.Equal(t, SymbolConfig{Pair: ParsePair("btc_usdt"), AmountScale: 4, PriceScale: 2}, symbols["btc_usdt"])
+		delete(symbols, "btc_usdt")
+	}
+	assert.Equal(t, int32(1), hits)
+}
+
+func TestZbHttpClient_GetAllTickers(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data/v1/markets":
+			w.Write([]byte(`{"btc_usdt":{"amountScale":4,"priceScale":2},"eth_btc":{"amountScale":3,"priceScale":6}}`))
+		case "/data/v1/allTicker":
+			w.Write([]byte(`{"btcusdt":{"vol":"10","last":"11000","sell":"11001","buy":"10999","high":"12000","low":"10000"},"ethbtc":{"vol":"5","last":"0.1","sell":"0.11","buy":"0.09","high":"0.12","low":"0.08"},"unknown":{"last":"1"}}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	})
+	defer shutdown()
+
+	tickers, err := c.GetAllTickers()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(tickers))
+	ticker := tickers[ParsePair("btc_usdt")]
+	assert.Equal(t, Ticker{Amount: 10, Last: 11000, Ask: 11001, Bid: 10999, High: 12000, Low: 10000, Time: ticker.Time}, ticker)
+	assert.True(t, ticker.Time > 0)
+	assert.Equal(t, 0.1, tickers[ParsePair("eth_btc")].Last)
+}
+
+func TestZbHttpClient_GetOpenOrders(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "getUnfinishedOrdersIgnoreTradeType", r.URL.Query().Get("method"))
+		switch r.URL.Query().Get("pageIndex") {
+		case "1":
+			w.Write([]byte(`[` + strings.TrimSuffix(strings.Repeat(`{"id":"1","currency":"btc_usdt","price":11000,"status":0,"total_amount":0.1,"trade_amount":0,"type":1},`, 10), ",") + `]`))
+		case "2":
+			w.Write([]byte(`[{"id":"2","currency":"btc_usdt","price":12000,"status":3,"total_amount":0.2,"trade_amount":0.1,"type":0}]`))
+		default:
+			t.Errorf("unexpected page %s", r.URL.Query().Get("pageIndex"))
+		}
+	})
+	defer shutdown()
+
+	orders, err := c.GetOpenOrders("btc_usdt", accessKey, secretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, 11, len(orders))
+	assert.Equal(t, Order{Id: 2, Price: 12000, TotalAmount: 0.2, TradeAmount: 0.1, Symbol: "btc_usdt", Status: PartiallyFilled, TradeType: Sell}, orders[10])
+}
+
+func TestZbHttpClient_GetOpenOrdersEmpty(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":3001,"message":"not found"}`))
+	})
+	defer shutdown()
+
+	orders, err := c.GetOpenOrders("btc_usdt", accessKey, secretKey)
+	assert.Nil(t, err)
+	assert.Empty(t, orders)
+}
+
+func TestZbHttpClient_PlaceOrders(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assert.Equal(t, "order", q.Get("method"))
+		if q.Get("price") == "0" {
+			w.Write([]byte(`{"code":3002,"message":"invalid price"}`))
+			return
+		}
+		w.Write([]byte(`{"code":1000,"message":"success","id":"` + q.Get("tradeType") + q.Get("price") + `"}`))
+	})
+	defer shutdown()
+
+	results := c.PlaceOrders("btc_usdt", []OrderRequest{{Price: 11000, Amount: 0.1, TradeType: Buy}, {Price: 0, Amount: 0.1, TradeType: Buy}, {Price: 12000, Amount: 0.1, TradeType: Sell}}, accessKey, secretKey)
+	assert.Equal(t, 3, len(results))
+	assert.Equal(t, OrderResult{Id: 111000}, results[0])
+	assert.Equal(t, InvalidPrice, results[1].Err.(*ApiError).Code)
+	assert.Equal(t, OrderResult{Id: 12000}, results[2])
+}
+
+func TestZbHttpClient_CancelAllOrders(t *testing.T) {
+	var cancelled []string
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch q.Get("method") {
+		case "getUnfinishedOrdersIgnoreTradeType":
+			w.Write([]byte(`[{"id":"1","currency":"btc_usdt"},{"id":"2","currency":"btc_usdt"}]`))
+		case "cancelOrder":
+			cancelled = append(cancelled, q.Get("id"))
+			if q.Get("id") == "2" {
+				w.Write([]byte(`{"code":3001,"message":"not found"}`))
+				return
+			}
+			w.Write([]byte(`{"code":1000,"message":"success"}`))
+		}
+	})
+	defer shutdown()
+
+	results, err := c.CancelAllOrders("btc_usdt", accessKey, secretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1", "2"}, cancelled)
+	assert.Equal(t, OrderResult{Id: 1}, results[0])
+	assert.Equal(t, uint64(2), results[1].Id)
+	assert.Equal(t, OrderNotFound, results[1].Err.(*ApiError).Code)
+}
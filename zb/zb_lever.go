@@ -0,0 +1,135 @@
+package zb
+
+import (
+	. "github.com/berryland/x"
+	json "github.com/buger/jsonparser"
+	"math"
+	"strings"
+)
+
+var loanStatuses = map[int64]LoanStatus{
+	1: LoanAccrual,
+	2: LoanCleared,
+	3: LoanAccrual,
+}
+
+// GetLeverAccounts returns every lever account, the base and the quote currency of a market are the two assets.
+// ZB reports no risk rate, so RiskRate is always zero.
+func (c *ZbHttpClient) GetLeverAccounts(accessKey, secretKey string) ([]MarginAccount, error) {
+	bytes, err := c.getTrade("getLeverAssetsInfo", Query{}, accessKey, secretKey)
+	if err != nil {
+		return []MarginAccount{}, err
+	}
+
+	var accounts []MarginAccount
+	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+		key, _ := json.GetString(value, "key")
+		accounts = append(accounts, MarginAccount{
+			Symbol:           key,
+			LiquidationPrice: getFloat(value, "unwindPrice"),
+			Assets:           []MarginAsset{marshalLeverAsset(value, "f"), marshalLeverAsset(value, "c")},
+		})
+	}, "message", "datas", "levers")
+
+	return accounts, nil
+}
+
+func (c *ZbHttpClient) TransferInLever(symbol string, currency string, amount float64, accessKey, secretKey string) error {
+	return c.transferLever("transferInLever", symbol, currency, amount, accessKey, secretKey)
+}
+
+func (c *ZbHttpClient) TransferOutLever(symbol string, currency string, amount float64, accessKey, secretKey string) error {
+	return c.transferLever("transferOutLever", symbol, currency, amount, accessKey, secretKey)
+}
+
+// Borrow borrows amount of currency into the lever account of symbol for days, loop renews the loan automatically when
+// it is due. interestRate is the daily rate as a fraction like LoanOrder.InterestRate, 0.001 for 0.1% a day.
+func (c *ZbHttpClient) Borrow(symbol string, currency string, amount, interestRate float64, days uint8, loop bool, safePwd string, accessKey, secretKey string) error {
+	q := Query{
+		"marketName":        symbol,
+		"coin":              currency,
+		"amount":            amount,
+		"interestRateOfDay": math.Round(interestRate*1e10) / 1e8,
+		"repaymentDay":      days,
+		"isLoop":            0,
+		"safePwd":           safePwd,
+	}
+	if loop {
+		q["isLoop"] = 1
+	}
+
+	_, err := c.getTrade("borrow", q, accessKey, secretKey)
+	return err
+}
+
+func (c *ZbHttpClient) Repay(id uint64, amount float64, safePwd string, accessKey, secretKey string) error {
+	q := Query{
+		"loanRecordId": id,
+		"repayAmount":  amount,
+		"repayType":    0,
+		"safePwd":      safePwd,
+	}
+
+	_, err := c.getTrade("repay", q, accessKey, secretKey)
+	return err
+}
+
+// GetLoanRecords returns a page of the loans taken in the lever account of symbol, pages start at 1. ZB reports the
+// interest rate in percent, it is converted to the fraction LoanOrder holds.
+func (c *ZbHttpClient) GetLoanRecords(symbol string, page uint64, size uint16, accessKey, secretKey string) ([]LoanOrder, error) {
+	q := Query{
+		"marketName": symbol,
+		"pageIndex":  page,
+		"pageSize":   size,
+	}
+	bytes, err := c.getTrade("getLoanRecords", q, accessKey, secretKey)
+	if err != nil {
+		return []LoanOrder{}, err
+	}
+
+	var orders []LoanOrder
+	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+		id, _ := json.GetInt(value, "id")
+		status, _ := json.GetInt(value, "status")
+		createTime, _ := json.GetInt(value, "createTime")
+		marketName, _ := json.GetString(value, "marketName")
+		coinName, _ := json.GetString(value, "coinName")
+		amount := getFloat(value, "amount")
+		arrearsInterest := getFloat(value, "arrearsInterest")
+		orders = append(orders, LoanOrder{
+			Id:              uint64(id),
+			Symbol:          marketName,
+			Currency:        ParseCurrency(strings.ToLower(coinName)),
+			Amount:          amount,
+			Balance:         amount - getFloat(value, "hasRepay"),
+			InterestRate:    getFloat(value, "interestRateOfDay") / 100,
+			Interest:        getFloat(value, "hasInterest") + arrearsInterest,
+			InterestBalance: arrearsInterest,
+			Status:          loanStatuses[status],
+			Time:            uint64(createTime),
+		})
+	}, "message", "datas", "list")
+
+	return orders, nil
+}
+
+func (c *ZbHttpClient) transferLever(method string, symbol string, currency string, amount float64, accessKey, secretKey string) error {
+	q := Query{
+		"marketName": symbol,
+		"coin":       currency,
+		"amount":     amount,
+	}
+
+	_, err := c.getTrade(method, q, accessKey, secretKey)
+	return err
+}
+
+func marshalLeverAsset(value []byte, prefix string) MarginAsset {
+	name, _ := json.GetString(value, prefix+"EnName")
+	return MarginAsset{
+		Currency:  ParseCurrency(strings.ToLower(name)),
+		Available: getFloat(value, prefix+"Available"),
+		Freeze:    getFloat(value, prefix+"Freeze"),
+		Loan:      getFloat(value, prefix+"Overdraft"),
+	}
+}
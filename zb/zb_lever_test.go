@@ -0,0 +1,107 @@
+package zb
+
+import (
+	. "github.com/berryland/x"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestZbHttpClient_GetLeverAccounts(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "getLeverAssetsInfo", r.URL.Query().Get("method"))
+		assert.NotEmpty(t, r.URL.Query().Get("sign"))
+		w.Write([]byte(`{"code":1000,"message":{"des":"success","isSuc":true,"datas":{"levers":[{"fLoanIn":0,"repayLevel":0,"cUnitTip":"₮","unwindPrice":5800.5,"fUnitDecimal":8,"repayLock":false,"cLoanIn":0,"cEnName":"USDT","cAvailable":"1200.5","fAvailable":"0.5","cLoanOut":0,"cCanLoanIn":0,"fLoanOut":0,"level":0,"fFreeze":"0.1","fEnName":"BTC","cFreeze":"0","cOverdraft":"1000","key":"btcusdt","fOverdraft":0}]}}}`))
+	})
+	defer shutdown()
+
+	accounts, err := c.GetLeverAccounts(accessKey, secretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []MarginAccount{{
+		Symbol:           "btcusdt",
+		LiquidationPrice: 5800.5,
+		Assets: []MarginAsset{
+			{Currency: ParseCurrency("btc"), Available: 0.5, Freeze: 0.1},
+			{Currency: ParseCurrency("usdt"), Available: 1200.5, Loan: 1000},
+		},
+	}}, accounts)
+}
+
+func TestZbHttpClient_TransferInLever(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assert.Equal(t, "transferInLever", q.Get("method"))
+		assert.Equal(t, "btcusdt", q.Get("marketName"))
+		assert.Equal(t, "usdt", q.Get("coin"))
+		assert.Equal(t, "100", q.Get("amount"))
+		w.Write([]byte(`{"code":1000,"message":"success"}`))
+	})
+	defer shutdown()
+
+	assert.Nil(t, c.TransferInLever("btcusdt", "usdt", 100, accessKey, secretKey))
+}
+
+func TestZbHttpClient_Borrow(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch q.Get("method") {
+		case "borrow":
+			assert.Equal(t, "btcusdt", q.Get("marketName"))
+			assert.Equal(t, "usdt", q.Get("coin"))
+			assert.Equal(t, "1000", q.Get("amount"))
+			assert.Equal(t, "0.1", q.Get("interestRateOfDay"))
+			assert.Equal(t, "10", q.Get("repaymentDay"))
+			assert.Equal(t, "1", q.Get("isLoop"))
+			w.Write([]byte(`{"code":1000,"message":"success"}`))
+		case "repay":
+			assert.Equal(t, "2017", q.Get("loanRecordId"))
+			assert.Equal(t, "500", q.Get("repayAmount"))
+			w.Write([]byte(`{"code":2009,"message":"insufficient balance"}`))
+		}
+	})
+	defer shutdown()
+
+	assert.Nil(t, c.Borrow("btcusdt", "usdt", 1000, 0.001, 10, true, "123456", accessKey, secretKey))
+	err := c.Repay(2017, 500, "123456", accessKey, secretKey)
+	assert.Equal(t, InsufficientFund, err.(*ApiError).Code)
+}
+
+func TestZbHttpClient_BorrowInterestRate(t *testing.T) {
+	var rates []string
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		rates = append(rates, r.URL.Query().Get("interestRateOfDay"))
+		w.Write([]byte(`{"code":1000,"message":"success"}`))
+	})
+	defer shutdown()
+
+	for _, rate := range []float64{0.0007, 0.00015} {
+		assert.Nil(t, c.Borrow("btcusdt", "usdt", 1000, rate, 10, false, "123456", accessKey, secretKey))
+	}
+	assert.Equal(t, []string{"0.07", "0.015"}, rates)
+}
+
+func TestZbHttpClient_GetLoanRecords(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assert.Equal(t, "getLoanRecords", q.Get("method"))
+		assert.Equal(t, "btcusdt", q.Get("marketName"))
+		assert.Equal(t, "1", q.Get("pageIndex"))
+		w.Write([]byte(`{"code":1000,"message":{"des":"success","isSuc":true,"datas":{"list":[{"id":2017,"loanId":1200,"marketName":"btcusdt","coinName":"USDT","amount":"1000","hasRepay":"400","interestRateOfDay":"0.1","hasInterest":"2","arrearsInterest":"1","status":1,"createTime":1517454000000}]}}}`))
+	})
+	defer shutdown()
+
+	records, err := c.GetLoanRecords("btcusdt", 1, 10, accessKey, secretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []LoanOrder{{
+		Id:              2017,
+		Symbol:          "btcusdt",
+		Currency:        ParseCurrency("usdt"),
+		Amount:          1000,
+		Balance:         600,
+		InterestRate:    0.001,
+		Interest:        3,
+		InterestBalance: 1,
+		Status:          LoanAccrual,
+		Time:            1517454000000,
+	}}, records)
+}
@@ -0,0 +1,122 @@
+package zb
+
+import (
+	. "github.com/berryland/x"
+	"time"
+)
+
+const (
+	defaultOrderPageSize  = 10
+	maxTooFrequentRetries = 5
+)
+
+// OrderQuery bounds an order history walk. Since is inclusive and Until exclusive, zero leaves the bound open.
+// TradeType restricts the walk to one side when set, nil walks both buy and sell orders.
+type OrderQuery struct {
+	TradeType *TradeType
+	Since     uint64
+	Until     uint64
+	PageSize  uint16
+}
+
+// OrderIterator walks the order history page by page, newest first.
+type OrderIterator struct {
+	Limiter   *Limiter
+	client    *ZbHttpClient
+	symbol    string
+	query     OrderQuery
+	accessKey string
+	secretKey string
+	page      uint64
+	orders    []Order
+	order     Order
+	seen      map[uint64]bool
+	done      bool
+	err       error
+}
+
+func (c *ZbHttpClient) IterateOrders(symbol string, query OrderQuery, accessKey, secretKey string) *OrderIterator {
+	if query.PageSize == 0 {
+		query.PageSize = defaultOrderPageSize
+	}
+	return &OrderIterator{
+		Limiter:   NewLimiter(100 * time.Millisecond),
+		client:    c,
+		symbol:    symbol,
+		query:     query,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		page:      1,
+		seen:      map[uint64]bool{},
+	}
+}
+
+func (it *OrderIterator) Next() bool {
+	for len(it.orders) == 0 {
+		if it.done {
+			return false
+		}
+		it.fetch()
+	}
+
+	it.order, it.orders = it.orders[0], it.orders[1:]
+	return true
+}
+
+func (it *OrderIterator) Order() Order {
+	return it.order
+}
+
+func (it *OrderIterator) Err() error {
+	return it.err
+}
+
+func (it *OrderIterator) fetch() {
+	orders, err := it.getOrders()
+	if e, ok := err.(*ApiError); ok && e.Code == OrderNotFound {
+		it.done = true
+		return
+	}
+	if err != nil {
+		it.err, it.done = err, true
+		return
+	}
+
+	it.page++
+	if len(orders) < int(it.query.PageSize) {
+		it.done = true
+	}
+
+	for _, order := range orders {
+		if it.query.Since > 0 && order.Time < it.query.Since {
+			it.done = true
+			break
+		}
+		if it.query.Until > 0 && order.Time >= it.query.Until || it.seen[order.Id] {
+			continue
+		}
+		it.seen[order.Id] = true
+		it.orders = append(it.orders, order)
+	}
+}
+
+func (it *OrderIterator) tradeType() TradeType {
+	if it.query.TradeType == nil {
+		return All
+	}
+	return *it.query.TradeType
+}
+
+func (it *OrderIterator) getOrders() ([]Order, error) {
+	backoff := 100 * time.Millisecond
+	for retries := 0; ; retries++ {
+		it.Limiter.Wait()
+		orders, err := it.client.GetOrders(it.symbol, it.tradeType(), it.page, it.query.PageSize, it.accessKey, it.secretKey)
+		if e, ok := err.(*ApiError); !ok || e.Code != TooFrequent || retries == maxTooFrequentRetries {
+			return orders, err
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
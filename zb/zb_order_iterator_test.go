@@ -0,0 +1,108 @@
+package zb
+
+import (
+	"fmt"
+	. "github.com/berryland/x"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func orderHistoryHandler(total int, requests *[]string) http.HandlerFunc {
+	tooFrequent := true
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		*requests = append(*requests, q.Get("method")+"/"+q.Get("pageIndex"))
+		if q.Get("pageIndex") == "2" && tooFrequent {
+			tooFrequent = false
+			w.Write([]byte(`{"code":4002,"message":"too frequent"}`))
+			return
+		}
+
+		page, _ := strconv.Atoi(q.Get("pageIndex"))
+		size, _ := strconv.Atoi(q.Get("pageSize"))
+		var orders []string
+		for i := (page - 1) * size; i < page*size && i < total; i++ {
+			orders = append(orders, fmt.Sprintf(`{"id":"%d","currency":"btc_usdt","trade_date":%d,"type":1}`, total-i, 1000*(total-i)))
+		}
+		if len(orders) == 0 {
+			w.Write([]byte(`{"code":3001,"message":"not found"}`))
+			return
+		}
+		w.Write([]byte("[" + strings.Join(orders, ",") + "]"))
+	}
+}
+
+func TestOrderIterator(t *testing.T) {
+	var requests []string
+	c, shutdown := newTestHttpClient(orderHistoryHandler(25, &requests))
+	defer shutdown()
+
+	it := c.IterateOrders("btc_usdt", OrderQuery{}, accessKey, secretKey)
+	it.Limiter = nil
+	var ids []uint64
+	for it.Next() {
+		ids = append(ids, it.Order().Id)
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, 25, len(ids))
+	assert.Equal(t, uint64(25), ids[0])
+	assert.Equal(t, uint64(1), ids[24])
+	assert.Equal(t, []string{"getOrdersIgnoreTradeType/1", "getOrdersIgnoreTradeType/2", "getOrdersIgnoreTradeType/2", "getOrdersIgnoreTradeType/3"}, requests)
+}
+
+func TestOrderIterator_LastFullPage(t *testing.T) {
+	var requests []string
+	c, shutdown := newTestHttpClient(orderHistoryHandler(20, &requests))
+	defer shutdown()
+
+	buy := Buy
+	it := c.IterateOrders("btc_usdt", OrderQuery{TradeType: &buy}, accessKey, secretKey)
+	it.Limiter = nil
+	count := 0
+	for it.Next() {
+		count++
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, 20, count)
+	assert.Equal(t, "getOrdersNew/3", requests[len(requests)-1])
+}
+
+func TestOrderIterator_TimeBounds(t *testing.T) {
+	var requests []string
+	c, shutdown := newTestHttpClient(orderHistoryHandler(100, &requests))
+	defer shutdown()
+
+	it := c.IterateOrders("btc_usdt", OrderQuery{Since: 75000, Until: 90000}, accessKey, secretKey)
+	it.Limiter = nil
+	var ids []uint64
+	for it.Next() {
+		ids = append(ids, it.Order().Id)
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, 15, len(ids))
+	assert.Equal(t, uint64(89), ids[0])
+	assert.Equal(t, uint64(75), ids[14])
+	assert.Equal(t, "getOrdersIgnoreTradeType/3", requests[len(requests)-1])
+}
+
+func TestOrderIterator_Error(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":1003,"message":"auth failed"}`))
+	})
+	defer shutdown()
+
+	it := c.IterateOrders("btc_usdt", OrderQuery{}, accessKey, secretKey)
+	assert.False(t, it.Next())
+	assert.Equal(t, AuthenticationFailed, it.Err().(*ApiError).Code)
+}
+
+func TestOrderIterator_UnsupportedTradeType(t *testing.T) {
+	c := NewHttpClient()
+	tradeType := TradeType(7)
+	it := c.IterateOrders("btc_usdt", OrderQuery{TradeType: &tradeType}, accessKey, secretKey)
+	assert.False(t, it.Next())
+	assert.EqualError(t, it.Err(), "Unsupported trade type: 7")
+}
@@ -0,0 +1,59 @@
+package zb
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"fmt"
+	. "github.com/berryland/x"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ZbSigner struct {
+	AccessKey string
+	SecretKey string
+	Clock     Clock
+}
+
+func NewSigner(accessKey, secretKey string) *ZbSigner {
+	return &ZbSigner{AccessKey: accessKey, SecretKey: secretKey}
+}
+
+// Sign adds accesskey, sign and reqTime to query. reqTime is not covered by the signature.
+func (s *ZbSigner) Sign(method string, rawUrl string, query Query) error {
+	delete(query, "sign")
+	delete(query, "reqTime")
+	query["accesskey"] = s.AccessKey
+	query.Encode()
+	query["sign"] = genSign(s.SecretKey, query)
+	query["reqTime"] = strconv.FormatInt(s.Clock.Now().UnixNano()/int64(time.Millisecond), 10)
+	return nil
+}
+
+func genSign(secretKey string, params map[string]interface{}) string {
+	return genSignBytes(secretKey, []byte(getSortedQueryString(params)))
+}
+
+func genSignBytes(secretKey string, payload []byte) string {
+	h := hmac.New(md5.New, []byte(fmt.Sprintf("%x", sha1.Sum([]byte(secretKey)))))
+	h.Write(payload)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func getSortedQueryString(params map[string]interface{}) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var kvs []string
+	for _, k := range keys {
+		kvs = append(kvs, fmt.Sprintf("%v=%v", k, params[k]))
+	}
+
+	return strings.Join(kvs, "&")
+}
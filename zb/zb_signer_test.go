@@ -0,0 +1,68 @@
+package zb
+
+import (
+	. "github.com/berryland/x"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const (
+	vectorAccessKey = "6d8f62fd-3086-46e3-a0ba-c66a929c24ea"
+	vectorSecretKey = "0c8a9e4f-3c56-4c2b-9d5b-1b2d5e7f8a90"
+)
+
+// signVectors were computed with openssl rather than by genSign, e.g. for the first one:
+//
+//	printf '%s' 'accesskey=6d8f62fd-3086-46e3-a0ba-c66a929c24ea&method=getAccountInfo' |
+//		openssl dgst -md5 -hmac "$(printf '%s' 0c8a9e4f-3c56-4c2b-9d5b-1b2d5e7f8a90 | openssl dgst -sha1 -r | cut -d' ' -f1)"
+var signVectors = []struct {
+	query   Query
+	payload string
+	sign    string
+}{
+	{
+		Query{"method": "getAccountInfo"},
+		"accesskey=6d8f62fd-3086-46e3-a0ba-c66a929c24ea&method=getAccountInfo",
+		"a1e896d78c53f06260c4fb8ce1d455c1",
+	},
+	{
+		Query{"method": "order", "currency": "btc_usdt", "price": 15000, "amount": 0.01, "tradeType": "0"},
+		"accesskey=6d8f62fd-3086-46e3-a0ba-c66a929c24ea&amount=0.01&currency=btc_usdt&method=order&price=15000&tradeType=0",
+		"3b50a66ea1a3912f1c671c142db2a478",
+	},
+	{
+		Query{"method": "getOrdersIgnoreTradeType", "currency": "eth_btc", "pageIndex": uint64(1), "pageSize": uint16(10)},
+		"accesskey=6d8f62fd-3086-46e3-a0ba-c66a929c24ea&currency=eth_btc&method=getOrdersIgnoreTradeType&pageIndex=1&pageSize=10",
+		"d4ead9e6cdd68885209dbf9df6651302",
+	},
+}
+
+func TestZbSigner_Sign(t *testing.T) {
+	s := NewSigner(vectorAccessKey, vectorSecretKey)
+	s.Clock = func() time.Time { return time.Unix(1516536000, 0) }
+
+	for _, v := range signVectors {
+		q := v.query.Clone()
+		assert.Nil(t, s.Sign("GET", TradeApiUrl, q))
+		assert.Equal(t, v.sign, q["sign"])
+		assert.Equal(t, "1516536000000", q["reqTime"])
+		assert.Equal(t, vectorAccessKey, q["accesskey"])
+	}
+}
+
+func TestZbSigner_SignTwice(t *testing.T) {
+	s := NewSigner(vectorAccessKey, vectorSecretKey)
+	q := Query{"method": "getAccountInfo"}
+	s.Sign("GET", TradeApiUrl, q)
+	s.Sign("GET", TradeApiUrl, q)
+	assert.Equal(t, signVectors[0].sign, q["sign"])
+}
+
+func TestGetSortedQueryString(t *testing.T) {
+	for _, v := range signVectors {
+		q := v.query.Clone()
+		q["accesskey"] = vectorAccessKey
+		assert.Equal(t, v.payload, getSortedQueryString(q.Encode()))
+	}
+}
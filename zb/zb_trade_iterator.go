@@ -0,0 +1,122 @@
+package zb
+
+import (
+	. "github.com/berryland/x"
+	"sort"
+	"time"
+)
+
+// TradeCheckpoint is the state needed to resume a TradeIterator, LastId is the id of the last trade returned.
+type TradeCheckpoint struct {
+	Symbol string
+	LastId uint64
+}
+
+// TradeIterator walks trades forward by id until it catches up with the latest trade.
+type TradeIterator struct {
+	Limiter *Limiter
+	client  *ZbHttpClient
+	symbol  string
+	lastId  uint64
+	trades  []Trade
+	trade   Trade
+	done    bool
+	err     error
+}
+
+// IterateTrades walks trades starting with id, zero starts from the latest batch.
+func (c *ZbHttpClient) IterateTrades(symbol string, id uint64) *TradeIterator {
+	if id > 0 {
+		id--
+	}
+	return c.ResumeTrades(TradeCheckpoint{Symbol: symbol, LastId: id})
+}
+
+// IterateTradesSince walks trades made at or after since, the first trade id is found by a binary search over ids.
+func (c *ZbHttpClient) IterateTradesSince(symbol string, since uint64) (*TradeIterator, error) {
+	limiter := NewLimiter(100 * time.Millisecond)
+	latest, err := c.GetTrades(symbol, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	lo, hi := uint64(0), uint64(0)
+	for _, trade := range latest {
+		if trade.Id >= hi {
+			hi = trade.Id + 1
+		}
+	}
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		limiter.Wait()
+		trades, err := c.GetTrades(symbol, mid)
+		if err != nil {
+			return nil, err
+		}
+
+		first, found := Trade{}, false
+		for _, trade := range trades {
+			if trade.Id >= mid && (!found || trade.Id < first.Id) {
+				first, found = trade, true
+			}
+		}
+		if !found || first.Time >= since {
+			hi = mid
+		} else {
+			lo = first.Id + 1
+		}
+	}
+	return c.IterateTrades(symbol, lo), nil
+}
+
+func (c *ZbHttpClient) ResumeTrades(checkpoint TradeCheckpoint) *TradeIterator {
+	return &TradeIterator{Limiter: NewLimiter(100 * time.Millisecond), client: c, symbol: checkpoint.Symbol, lastId: checkpoint.LastId}
+}
+
+func (it *TradeIterator) Next() bool {
+	if len(it.trades) == 0 && !it.done {
+		it.fetch()
+	}
+	if len(it.trades) == 0 {
+		return false
+	}
+
+	it.trade, it.trades = it.trades[0], it.trades[1:]
+	it.lastId = it.trade.Id
+	return true
+}
+
+func (it *TradeIterator) Trade() Trade {
+	return it.trade
+}
+
+func (it *TradeIterator) Err() error {
+	return it.err
+}
+
+func (it *TradeIterator) Checkpoint() TradeCheckpoint {
+	return TradeCheckpoint{Symbol: it.symbol, LastId: it.lastId}
+}
+
+func (it *TradeIterator) fetch() {
+	it.Limiter.Wait()
+	trades, err := it.client.GetTrades(it.symbol, it.lastId)
+	if err != nil {
+		it.err, it.done = err, true
+		return
+	}
+
+	seen := map[uint64]bool{}
+	for _, trade := range trades {
+		if trade.Id > it.lastId && !seen[trade.Id] {
+			seen[trade.Id] = true
+			it.trades = append(it.trades, trade)
+		}
+	}
+	sort.Slice(it.trades, func(i, j int) bool {
+		return it.trades[i].Id < it.trades[j].Id
+	})
+	if len(it.trades) == 0 {
+		it.done = true
+	}
+}
@@ -0,0 +1,89 @@
+package zb
+
+import (
+	"fmt"
+	. "github.com/berryland/x"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// tradesHandler serves trades 1..*total, trade i is made at i*1000, up to 50 trades after since like the trades api.
+func tradesHandler(total *uint64, requests *[]uint64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		since, _ := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+		*requests = append(*requests, since)
+		from := since
+		if since == 0 && *total > 50 {
+			from = *total - 50
+		}
+
+		var trades []string
+		for id := from + 1; id <= *total && len(trades) < 50; id++ {
+			trades = append(trades, fmt.Sprintf(`{"amount":"0.1","price":"11000","tid":%d,"date":%d,"type":"buy","trade_type":"bid"}`, id, id*1000))
+		}
+		w.Write([]byte("[" + strings.Join(trades, ",") + "]"))
+	}
+}
+
+func TestTradeIterator(t *testing.T) {
+	total := uint64(120)
+	var requests []uint64
+	c, shutdown := newTestHttpClient(tradesHandler(&total, &requests))
+	defer shutdown()
+
+	it := c.IterateTrades("btc_usdt", 11)
+	it.Limiter = nil
+	var ids []uint64
+	for it.Next() {
+		ids = append(ids, it.Trade().Id)
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, 110, len(ids))
+	assert.Equal(t, uint64(11), ids[0])
+	assert.Equal(t, uint64(120), ids[109])
+	assert.Equal(t, []uint64{10, 60, 110, 120}, requests)
+	assert.Equal(t, TradeCheckpoint{Symbol: "btc_usdt", LastId: 120}, it.Checkpoint())
+
+	total = 130
+	resumed := c.ResumeTrades(it.Checkpoint())
+	resumed.Limiter = nil
+	ids = nil
+	for resumed.Next() {
+		ids = append(ids, resumed.Trade().Id)
+	}
+	assert.Equal(t, 10, len(ids))
+	assert.Equal(t, uint64(121), ids[0])
+}
+
+func TestTradeIterator_Since(t *testing.T) {
+	total := uint64(1000)
+	var requests []uint64
+	c, shutdown := newTestHttpClient(tradesHandler(&total, &requests))
+	defer shutdown()
+
+	it, err := c.IterateTradesSince("btc_usdt", 987500)
+	assert.Nil(t, err)
+	it.Limiter = nil
+	var ids []uint64
+	for it.Next() {
+		ids = append(ids, it.Trade().Id)
+	}
+	assert.Equal(t, 13, len(ids))
+	assert.Equal(t, uint64(988), ids[0])
+	assert.Equal(t, TradeCheckpoint{Symbol: "btc_usdt", LastId: 1000}, it.Checkpoint())
+}
+
+func TestTradeIterator_Error(t *testing.T) {
+	c, shutdown := newTestHttpClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error":"invalid market"}`))
+	})
+	defer shutdown()
+
+	it := c.IterateTrades("btc_usdt", 1)
+	assert.False(t, it.Next())
+	assert.Equal(t, GeneralError, it.Err().(*ApiError).Code)
+	assert.Equal(t, TradeCheckpoint{Symbol: "btc_usdt", LastId: 0}, it.Checkpoint())
+}
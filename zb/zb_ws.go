@@ -1,24 +1,50 @@
 package zb
 
 import (
+	"context"
+	encoding "encoding/json"
 	. "github.com/berryland/x"
 	json "github.com/buger/jsonparser"
 	"github.com/gorilla/websocket"
-	"log"
 	"strings"
+	"sync"
+	"time"
 )
 
 const WebSocketServerUrl = "wss://api.zb.com:9999/websocket"
 
+// ZbWebSocketClient reconnects with Backoff whenever the connection drops after Connect, until Disconnect is called.
+// Every subscription is replayed on the new connection and OnGap is called for its channel, as pushes may have been
+// missed in between. Requests waiting for a response when the connection drops fail with ErrNotConnected.
+//
+// Its methods may be called from any goroutine. The exported fields are configuration and must be set before Connect.
 type ZbWebSocketClient struct {
-	running   bool
-	conn      *websocket.Conn
-	decoders  map[string]func([]byte) interface{}
-	callbacks map[string]func(interface{})
+	Url           string
+	Timeout       time.Duration
+	Hooks         []WsHook
+	Backoff       *Backoff
+	OnStateChange func(state ConnState)
+	OnGap         func(channel string)
+	running       bool
+	done          chan struct{}
+	conn          *websocket.Conn
+	decoders      map[string]func([]byte) interface{}
+	callbacks     map[string][]listener
+	listenerId    uint64
+	channels      map[string]*sync.Mutex
+	streams       map[*streamSubscription]struct{}
+	mutex         sync.Mutex
+	write         sync.Mutex
+	requesting    sync.Mutex
+	pending       map[string]pendingRequest
+	queued        map[string][]string
+	no            uint64
 }
 
+var _ WsApiClient = (*ZbWebSocketClient)(nil)
+
 func NewWebSocketClient() *ZbWebSocketClient {
-	return &ZbWebSocketClient{running: false, decoders: make(map[string]func([]byte) interface{}), callbacks: make(map[string]func(interface{}))}
+	return &ZbWebSocketClient{Url: WebSocketServerUrl, Timeout: 10 * time.Second, Backoff: NewBackoff(time.Second, time.Minute), running: false, decoders: make(map[string]func([]byte) interface{}), callbacks: make(map[string][]listener), channels: make(map[string]*sync.Mutex), streams: make(map[*streamSubscription]struct{}), pending: make(map[string]pendingRequest), queued: make(map[string][]string)}
 }
 
 type eventMessage struct {
@@ -26,69 +52,353 @@ type eventMessage struct {
 	Channel string `json:"channel"`
 }
 
-func (c *ZbWebSocketClient) Connect() {
+// Connect dials the server and starts reading, subscriptions made before are sent once connected. ctx only bounds
+// the first dial, later reconnects are bounded by Timeout.
+func (c *ZbWebSocketClient) Connect(ctx context.Context) error {
+	c.mutex.Lock()
 	if c.running {
-		return
+		c.mutex.Unlock()
+		return nil
 	}
 	c.running = true
+	c.done = make(chan struct{})
+	c.mutex.Unlock()
 
-	dialer := &websocket.Dialer{}
-	conn, _, err := dialer.Dial(WebSocketServerUrl, nil)
-	c.conn = conn
+	c.setState(Connecting)
+	conn, err := c.dial(ctx)
 	if err != nil {
-		c.Disconnect()
-		log.Fatalln("Fail to connect to " + WebSocketServerUrl + ", error: " + err.Error())
+		c.stop()
+		return err
 	}
+	c.setState(Connected)
+	c.resubscribe(false)
 
-	go func() {
-		defer c.Disconnect()
-		for {
-			_, bytes, err := c.conn.ReadMessage()
-			if err != nil {
-				break
-			}
-
-			channel, _ := json.GetString(bytes, "channel")
-			if decoder, ok := c.decoders[channel]; ok {
-				value := decoder(bytes)
-				if callback, ok := c.callbacks[channel]; ok {
-					callback(value)
-				}
-			}
-		}
-	}()
+	go c.run(conn)
+	return nil
 }
 
+// Disconnect closes the connection and the channels of every stream, the other subscriptions stay registered and are
+// sent again by the next Connect.
 func (c *ZbWebSocketClient) Disconnect() {
+	c.stop()
+	c.closeStreams()
+}
+
+func (c *ZbWebSocketClient) stop() {
+	c.mutex.Lock()
 	if !c.running {
+		c.mutex.Unlock()
 		return
 	}
 	c.running = false
+	close(c.done)
 
 	if c.conn != nil {
 		c.conn.Close()
+		c.conn = nil
+	}
+	c.failPending()
+	c.mutex.Unlock()
+	c.setState(Disconnected)
+}
+
+func (c *ZbWebSocketClient) dial(ctx context.Context) (*websocket.Conn, error) {
+	conn, err := DialWebSocket(ctx, c.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.running {
+		conn.Close()
+		return nil, ErrNotConnected
+	}
+	c.conn = conn
+	return conn, nil
+}
+
+// run reads conn and the connections replacing it, listeners are called by a dispatcher so that a callback waiting
+// for the response of a request does not stop the reads delivering it.
+func (c *ZbWebSocketClient) run(conn *websocket.Conn) {
+	dispatcher := NewDispatcher()
+	defer dispatcher.Close()
+	for conn != nil {
+		c.read(conn, dispatcher)
+		conn = c.reconnect(conn)
 	}
 }
 
-func (c *ZbWebSocketClient) SubscribeTicker(symbol string, callback func(ticker Ticker)) {
+func (c *ZbWebSocketClient) read(conn *websocket.Conn, dispatcher *Dispatcher) {
+	for {
+		_, bytes, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		channel, _ := json.GetString(bytes, "channel")
+		c.notify(&Frame{Channel: channel, Payload: bytes, Time: time.Now()})
+		if c.respond(channel, bytes) {
+			continue
+		}
+
+		c.mutex.Lock()
+		decoder, ok := c.decoders[channel]
+		listeners := c.callbacks[channel]
+		c.mutex.Unlock()
+		if !ok {
+			continue
+		}
+
+		value := decoder(bytes)
+		dispatcher.Add(func() {
+			for _, l := range listeners {
+				l.callback(value)
+			}
+		})
+	}
+}
+
+// reconnect dials until it succeeds or Disconnect is called, then replays the subscriptions. It returns nil when
+// the client was disconnected.
+func (c *ZbWebSocketClient) reconnect(old *websocket.Conn) *websocket.Conn {
+	c.mutex.Lock()
+	if !c.running || c.conn != old {
+		c.mutex.Unlock()
+		return nil
+	}
+	c.conn = nil
+	done := c.done
+	c.failPending()
+	c.mutex.Unlock()
+	old.Close()
+	c.setState(Disconnected)
+
+	for attempt := 0; ; attempt++ {
+		c.setState(Connecting)
+		ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+		conn, err := c.dial(ctx)
+		cancel()
+		if err == nil {
+			c.setState(Connected)
+			c.resubscribe(true)
+			return conn
+		}
+
+		c.setState(Disconnected)
+		select {
+		case <-done:
+			return nil
+		case <-time.After(c.Backoff.Duration(attempt)):
+		}
+	}
+}
+
+// resubscribe sends every registered subscription, gap tells whether pushes may have been missed since the last connection.
+func (c *ZbWebSocketClient) resubscribe(gap bool) {
+	c.mutex.Lock()
+	var channels []string
+	for channel := range c.decoders {
+		channels = append(channels, channel)
+	}
+	c.mutex.Unlock()
+
+	for _, channel := range channels {
+		if !c.resend(channel) {
+			continue
+		}
+		if gap && c.OnGap != nil {
+			c.OnGap(channel)
+		}
+	}
+}
+
+// resend sends the addChannel event of channel again and reports whether it is still subscribed.
+func (c *ZbWebSocketClient) resend(channel string) bool {
+	lock := c.channelLock(channel)
+	lock.Lock()
+	defer lock.Unlock()
+
+	c.mutex.Lock()
+	_, subscribed := c.decoders[channel]
+	c.mutex.Unlock()
+	if subscribed {
+		c.send(channel, eventMessage{Event: "addChannel", Channel: channel})
+	}
+	return subscribed
+}
+
+func (c *ZbWebSocketClient) setState(state ConnState) {
+	if c.OnStateChange != nil {
+		c.OnStateChange(state)
+	}
+}
+
+func (c *ZbWebSocketClient) SubscribeTicker(symbol string, callback func(ticker Ticker)) (Subscription, error) {
 	channel := strings.Replace(symbol, "_", "", 1) + "_ticker"
-	c.register(channel, func(value []byte) interface{} {
+	return c.subscribe(channel, func(value []byte) interface{} {
 		return marshalTicker(value)
 	}, func(v interface{}) {
 		callback(v.(Ticker))
 	})
-	c.conn.WriteJSON(eventMessage{Event: "addChannel", Channel: channel})
 }
 
-func (c *ZbWebSocketClient) register(channel string, decoder func(value []byte) interface{}, callback func(interface{})) {
-	c.registerDecoder(channel, decoder)
-	c.registerCallback(channel, callback)
+func (c *ZbWebSocketClient) SubscribeDepth(symbol string, callback func(depth Depth)) (Subscription, error) {
+	channel := strings.Replace(symbol, "_", "", 1) + "_depth"
+	return c.subscribe(channel, func(value []byte) interface{} {
+		return marshalDepth(value)
+	}, func(v interface{}) {
+		callback(v.(Depth))
+	})
+}
+
+func (c *ZbWebSocketClient) SubscribeTrades(symbol string, callback func(trades []Trade)) (Subscription, error) {
+	channel := strings.Replace(symbol, "_", "", 1) + "_trades"
+	return c.subscribe(channel, func(value []byte) interface{} {
+		return marshalTrades(value, "data")
+	}, func(v interface{}) {
+		callback(v.([]Trade))
+	})
+}
+
+// SubscribeKline calls callback for every kline of period pushed for symbol, in the order they are received.
+func (c *ZbWebSocketClient) SubscribeKline(symbol string, period string, callback func(kline Kline)) (Subscription, error) {
+	channel := strings.Replace(symbol, "_", "", 1) + "_kline_" + period
+	return c.subscribe(channel, func(value []byte) interface{} {
+		return marshalKlines(value, "data")
+	}, func(v interface{}) {
+		for _, kline := range v.([]Kline) {
+			callback(kline)
+		}
+	})
+}
+
+type listener struct {
+	id       uint64
+	callback func(interface{})
+}
+
+type subscription struct {
+	client  *ZbWebSocketClient
+	channel string
+	id      uint64
+}
+
+// Unsubscribe removes the listener, the channel itself is removed once its last listener is gone.
+func (s *subscription) Unsubscribe() error {
+	lock := s.client.channelLock(s.channel)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !s.client.unregister(s.channel, s.id) {
+		return nil
+	}
+
+	err := s.client.send(s.channel, eventMessage{Event: "removeChannel", Channel: s.channel})
+	if err == ErrNotConnected {
+		return nil
+	}
+	return err
+}
+
+// subscribe adds a listener to channel and sends its addChannel event if it is the first one. Without a connection
+// the subscription stays queued and is sent by the next Connect or reconnect.
+func (c *ZbWebSocketClient) subscribe(channel string, decoder func(value []byte) interface{}, callback func(interface{})) (Subscription, error) {
+	lock := c.channelLock(channel)
+	lock.Lock()
+	defer lock.Unlock()
+
+	s, first := c.register(channel, decoder, callback)
+	if !first {
+		return s, nil
+	}
+
+	err := c.send(channel, eventMessage{Event: "addChannel", Channel: channel})
+	if err != nil && err != ErrNotConnected {
+		c.unregister(channel, s.id)
+		return nil, err
+	}
+	return s, nil
+}
+
+func (c *ZbWebSocketClient) send(channel string, message interface{}) error {
+	bytes, err := encoding.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	conn := c.conn
+	c.mutex.Unlock()
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	c.notify(&Frame{Outgoing: true, Channel: channel, Payload: RedactPayload(bytes), Time: time.Now()})
+	c.write.Lock()
+	defer c.write.Unlock()
+	return conn.WriteMessage(websocket.TextMessage, bytes)
+}
+
+func (c *ZbWebSocketClient) notify(frame *Frame) {
+	for _, hook := range c.Hooks {
+		hook(frame)
+	}
+}
+
+// register adds callback as a listener of channel and reports whether it is the first one.
+func (c *ZbWebSocketClient) register(channel string, decoder func(value []byte) interface{}, callback func(interface{})) (*subscription, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, subscribed := c.decoders[channel]
+	if !subscribed {
+		c.registerDecoder(channel, decoder)
+	}
+	c.listenerId++
+	c.registerCallback(channel, listener{id: c.listenerId, callback: callback})
+	return &subscription{client: c, channel: channel, id: c.listenerId}, !subscribed
 }
 
 func (c *ZbWebSocketClient) registerDecoder(channel string, decoder func(value []byte) interface{}) {
 	c.decoders[channel] = decoder
 }
 
-func (c *ZbWebSocketClient) registerCallback(channel string, callback func(interface{})) {
-	c.callbacks[channel] = callback
+func (c *ZbWebSocketClient) registerCallback(channel string, l listener) {
+	c.callbacks[channel] = append(c.callbacks[channel], l)
+}
+
+// unregister removes the listener id of channel and reports whether it was the last one.
+func (c *ZbWebSocketClient) unregister(channel string, id uint64) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	listeners := c.callbacks[channel]
+	for i, l := range listeners {
+		if l.id != id {
+			continue
+		}
+
+		// copy so that a reader holding the old slice is not affected
+		remaining := append(append([]listener(nil), listeners[:i]...), listeners[i+1:]...)
+		if len(remaining) > 0 {
+			c.callbacks[channel] = remaining
+			return false
+		}
+		delete(c.callbacks, channel)
+		delete(c.decoders, channel)
+		return true
+	}
+	return false
+}
+
+// channelLock returns the lock serializing the addChannel and removeChannel events of channel with the listener
+// changes they follow.
+func (c *ZbWebSocketClient) channelLock(channel string) *sync.Mutex {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	lock, ok := c.channels[channel]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.channels[channel] = lock
+	}
+	return lock
 }
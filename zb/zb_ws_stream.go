@@ -0,0 +1,116 @@
+package zb
+
+import . "github.com/berryland/x"
+
+// The Stream methods are an alternative to the callbacks of the Subscribe methods. Every stream has its own queue and
+// dispatch goroutine, so a slow reader only affects its own stream unless its policy is Block, which stalls every
+// listener of the client while the queue is full. The channel is closed by Unsubscribe or Disconnect.
+
+func (c *ZbWebSocketClient) StreamTicker(symbol string, options StreamOptions) (<-chan Ticker, Subscription, error) {
+	out := make(chan Ticker)
+	s, err := c.startStream(options, func(push func(interface{})) (Subscription, error) {
+		return c.SubscribeTicker(symbol, func(ticker Ticker) { push(ticker) })
+	}, func(v interface{}, done <-chan struct{}) {
+		select {
+		case out <- v.(Ticker):
+		case <-done:
+		}
+	}, func() { close(out) })
+	return out, s, err
+}
+
+func (c *ZbWebSocketClient) StreamDepth(symbol string, options StreamOptions) (<-chan Depth, Subscription, error) {
+	out := make(chan Depth)
+	s, err := c.startStream(options, func(push func(interface{})) (Subscription, error) {
+		return c.SubscribeDepth(symbol, func(depth Depth) { push(depth) })
+	}, func(v interface{}, done <-chan struct{}) {
+		select {
+		case out <- v.(Depth):
+		case <-done:
+		}
+	}, func() { close(out) })
+	return out, s, err
+}
+
+func (c *ZbWebSocketClient) StreamTrades(symbol string, options StreamOptions) (<-chan []Trade, Subscription, error) {
+	out := make(chan []Trade)
+	s, err := c.startStream(options, func(push func(interface{})) (Subscription, error) {
+		return c.SubscribeTrades(symbol, func(trades []Trade) { push(trades) })
+	}, func(v interface{}, done <-chan struct{}) {
+		select {
+		case out <- v.([]Trade):
+		case <-done:
+		}
+	}, func() { close(out) })
+	return out, s, err
+}
+
+func (c *ZbWebSocketClient) StreamKline(symbol string, period string, options StreamOptions) (<-chan Kline, Subscription, error) {
+	out := make(chan Kline)
+	s, err := c.startStream(options, func(push func(interface{})) (Subscription, error) {
+		return c.SubscribeKline(symbol, period, func(kline Kline) { push(kline) })
+	}, func(v interface{}, done <-chan struct{}) {
+		select {
+		case out <- v.(Kline):
+		case <-done:
+		}
+	}, func() { close(out) })
+	return out, s, err
+}
+
+type streamSubscription struct {
+	Subscription
+	client *ZbWebSocketClient
+	queue  *Queue
+}
+
+func (s *streamSubscription) Unsubscribe() error {
+	s.client.mutex.Lock()
+	delete(s.client.streams, s)
+	s.client.mutex.Unlock()
+
+	err := s.Subscription.Unsubscribe()
+	s.queue.Close()
+	return err
+}
+
+// startStream subscribes with a callback pushing into a new queue and starts the goroutine handing the queued values
+// to deliver, finish is called once the queue is closed.
+func (c *ZbWebSocketClient) startStream(options StreamOptions, subscribe func(push func(interface{})) (Subscription, error), deliver func(v interface{}, done <-chan struct{}), finish func()) (Subscription, error) {
+	q := NewQueue(options)
+	s, err := subscribe(q.Push)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &streamSubscription{Subscription: s, client: c, queue: q}
+	c.mutex.Lock()
+	c.streams[stream] = struct{}{}
+	c.mutex.Unlock()
+
+	go func() {
+		defer finish()
+		for {
+			v, ok := q.Pop()
+			if !ok {
+				return
+			}
+			deliver(v, q.Done())
+		}
+	}()
+	return stream, nil
+}
+
+// closeStreams unsubscribes every stream, which closes their channels.
+func (c *ZbWebSocketClient) closeStreams() {
+	c.mutex.Lock()
+	var streams []*streamSubscription
+	for s := range c.streams {
+		streams = append(streams, s)
+	}
+	c.mutex.Unlock()
+
+	for _, s := range streams {
+		s.Unsubscribe()
+	}
+}
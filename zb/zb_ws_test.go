@@ -1,14 +1,23 @@
 package zb
 
 import (
+	"context"
 	. "github.com/berryland/x"
+	"github.com/berryland/x/xtest"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
 func TestWebSocketClient_SubscribeTicker(t *testing.T) {
 	c := NewWebSocketClient()
-	c.Connect()
+	c.Connect(context.Background())
 	c.SubscribeTicker("btc_usdt", func(ticker Ticker) {
 		println(ticker.Time)
 		c.Disconnect()
@@ -16,3 +25,451 @@ func TestWebSocketClient_SubscribeTicker(t *testing.T) {
 
 	time.Sleep(10 * time.Second)
 }
+
+// newTestWebSocketClient connects to a server which hands every decoded client message to handler.
+func newTestWebSocketClient(t *testing.T, handler func(conn *websocket.Conn, message map[string]interface{})) (*ZbWebSocketClient, func()) {
+	url, shutdown := xtest.NewWebSocketServer(t, handler)
+	c := NewWebSocketClient()
+	c.Url = url
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return c, func() {
+		c.Disconnect()
+		shutdown()
+	}
+}
+
+func pushOnAddChannel(t *testing.T, channel string, push string) func(conn *websocket.Conn, message map[string]interface{}) {
+	return func(conn *websocket.Conn, message map[string]interface{}) {
+		assert.Equal(t, "addChannel", message["event"])
+		assert.Equal(t, channel, message["channel"])
+		conn.WriteMessage(websocket.TextMessage, []byte(push))
+	}
+}
+
+func TestZbWebSocketClient_SubscribeDepth(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, pushOnAddChannel(t, "btcusdt_depth", `{"asks":[[3112.56,0.053],[3112.5,1.2]],"dataType":"depth","bids":[[3110.1,0.5]],"channel":"btcusdt_depth","timestamp":1504510433}`))
+	defer shutdown()
+
+	depths := make(chan Depth, 1)
+	_, err := c.SubscribeDepth("btc_usdt", func(depth Depth) {
+		depths <- depth
+	})
+	assert.Nil(t, err)
+
+	select {
+	case depth := <-depths:
+		assert.Equal(t, Depth{
+			Asks: []DepthEntry{{Price: 3112.56, Amount: 0.053}, {Price: 3112.5, Amount: 1.2}},
+			Bids: []DepthEntry{{Price: 3110.1, Amount: 0.5}},
+			Time: 1504510433,
+		}, depth)
+	case <-time.After(time.Second):
+		t.Fatal("no depth received")
+	}
+}
+
+func TestZbWebSocketClient_SubscribeTrades(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, pushOnAddChannel(t, "btcusdt_trades", `{"data":[{"date":1443428902,"price":"1565.91","amount":"0.553","tid":37594617,"type":"sell","trade_type":"ask"}],"dataType":"trades","channel":"btcusdt_trades"}`))
+	defer shutdown()
+
+	trades := make(chan []Trade, 1)
+	_, err := c.SubscribeTrades("btc_usdt", func(t []Trade) {
+		trades <- t
+	})
+	assert.Nil(t, err)
+
+	select {
+	case received := <-trades:
+		assert.Equal(t, []Trade{{Id: 37594617, TradeType: Sell, Price: 1565.91, Amount: 0.553, Time: 1443428902}}, received)
+	case <-time.After(time.Second):
+		t.Fatal("no trades received")
+	}
+}
+
+func TestZbWebSocketClient_SubscribeKline(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, pushOnAddChannel(t, "btcusdt_kline_1min", `{"data":[[1472107500000,3840.46,3843.56,3840.46,3840.46,0.1],[1472107560000,3840.46,3841,3839,3839.5,2.5]],"channel":"btcusdt_kline_1min"}`))
+	defer shutdown()
+
+	klines := make(chan Kline, 2)
+	_, err := c.SubscribeKline("btc_usdt", "1min", func(kline Kline) {
+		klines <- kline
+	})
+	assert.Nil(t, err)
+
+	for _, expected := range []Kline{
+		{Time: 1472107500000, Open: 3840.46, High: 3843.56, Low: 3840.46, Close: 3840.46, Amount: 0.1},
+		{Time: 1472107560000, Open: 3840.46, High: 3841, Low: 3839, Close: 3839.5, Amount: 2.5},
+	} {
+		select {
+		case kline := <-klines:
+			assert.Equal(t, expected, kline)
+		case <-time.After(time.Second):
+			t.Fatal("no kline received")
+		}
+	}
+}
+
+func TestZbWebSocketClient_Reconnect(t *testing.T) {
+	var mutex sync.Mutex
+	var connections int
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		mutex.Lock()
+		connections++
+		first := connections == 1
+		mutex.Unlock()
+
+		assert.Equal(t, "btcusdt_depth", message["channel"])
+		if first {
+			conn.Close()
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"asks":[[3112.56,0.053]],"bids":[],"channel":"btcusdt_depth","timestamp":1504510433}`))
+	})
+	defer shutdown()
+
+	var states []ConnState
+	gaps := make(chan string, 1)
+	c.Backoff = NewBackoff(10*time.Millisecond, 100*time.Millisecond)
+	c.OnStateChange = func(state ConnState) {
+		mutex.Lock()
+		states = append(states, state)
+		mutex.Unlock()
+	}
+	c.OnGap = func(channel string) {
+		gaps <- channel
+	}
+
+	depths := make(chan Depth, 1)
+	_, err := c.SubscribeDepth("btc_usdt", func(depth Depth) {
+		depths <- depth
+	})
+	assert.Nil(t, err)
+
+	select {
+	case channel := <-gaps:
+		assert.Equal(t, "btcusdt_depth", channel)
+	case <-time.After(time.Second):
+		t.Fatal("no gap reported")
+	}
+	select {
+	case depth := <-depths:
+		assert.Equal(t, uint64(1504510433), depth.Time)
+	case <-time.After(time.Second):
+		t.Fatal("no depth received after reconnect")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, []ConnState{Disconnected, Connecting, Connected}, states)
+}
+
+// The tests below are meant to be run with -race, they exercise the client from many goroutines at once.
+
+func TestZbWebSocketClient_ConcurrentSubscribe(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		channel := message["channel"].(string)
+		for i := 0; i < 5; i++ {
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"channel":"`+channel+`","date":"1517454000000","ticker":{"last":"10"}}`))
+		}
+	})
+	defer shutdown()
+
+	symbols := []string{"btc_usdt", "eth_usdt", "ltc_usdt", "eos_usdt", "bch_usdt", "etc_usdt", "xrp_usdt", "qtum_usdt"}
+	var wg sync.WaitGroup
+	var received sync.WaitGroup
+	received.Add(len(symbols))
+	for _, symbol := range symbols {
+		wg.Add(1)
+		go func(symbol string) {
+			defer wg.Done()
+			var once sync.Once
+			_, err := c.SubscribeTicker(symbol, func(ticker Ticker) {
+				assert.Equal(t, 10.0, ticker.Last)
+				once.Do(received.Done)
+			})
+			assert.Nil(t, err)
+		}(symbol)
+	}
+	wg.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		received.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("not every subscription received its ticker")
+	}
+}
+
+func TestZbWebSocketClient_ConcurrentRequests(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"success":true,"code":1000,"channel":"`+message["channel"].(string)+`","no":"`+message["no"].(string)+`","data":{"id":"`+message["id"].(string)+`","status":2}}`))
+	})
+	defer shutdown()
+
+	var wg sync.WaitGroup
+	for i := uint64(1); i <= 20; i++ {
+		wg.Add(1)
+		go func(id uint64) {
+			defer wg.Done()
+			order, err := c.GetOrder("btc_usdt", id, "access", "secret")
+			assert.Nil(t, err)
+			assert.Equal(t, id, order.Id)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestZbWebSocketClient_ConcurrentDisconnect(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"channel":"`+message["channel"].(string)+`","date":"1517454000000","ticker":{"last":"10"}}`))
+	})
+	defer shutdown()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.SubscribeTicker("btc_usdt", func(ticker Ticker) {})
+		}()
+		go func() {
+			defer wg.Done()
+			c.Disconnect()
+		}()
+	}
+	wg.Wait()
+
+	_, err := c.SubscribeTicker("eth_usdt", func(ticker Ticker) {})
+	assert.Nil(t, err)
+	_, err = c.GetOrder("btc_usdt", 1, "access", "secret")
+	assert.Equal(t, ErrNotConnected, err)
+}
+
+func TestZbWebSocketClient_ConnectError(t *testing.T) {
+	c := NewWebSocketClient()
+	c.Url = "ws://127.0.0.1:1"
+	var states []ConnState
+	c.OnStateChange = func(state ConnState) {
+		states = append(states, state)
+	}
+
+	assert.NotNil(t, c.Connect(context.Background()))
+	assert.Equal(t, []ConnState{Connecting, Disconnected}, states)
+	_, err := c.SubscribeTicker("btc_usdt", func(ticker Ticker) {})
+	assert.Nil(t, err)
+}
+
+func TestZbWebSocketClient_SubscribeBeforeConnect(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		assert.JSONEq(t, `{"event":"addChannel","channel":"btcusdt_ticker"}`, string(data))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"channel":"btcusdt_ticker","date":"1517454000000","ticker":{"last":"10"}}`))
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	c := NewWebSocketClient()
+	c.Url = "ws" + strings.TrimPrefix(server.URL, "http")
+	tickers := make(chan Ticker, 1)
+	_, err := c.SubscribeTicker("btc_usdt", func(ticker Ticker) {
+		tickers <- ticker
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, c.Connect(context.Background()))
+	defer c.Disconnect()
+
+	select {
+	case ticker := <-tickers:
+		assert.Equal(t, uint64(1517454000000), ticker.Time)
+	case <-time.After(time.Second):
+		t.Fatal("queued subscription was not sent")
+	}
+}
+
+func TestZbWebSocketClient_Unsubscribe(t *testing.T) {
+	events := make(chan string, 4)
+	conns := make(chan *websocket.Conn, 1)
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		events <- message["event"].(string)
+		if message["event"] == "addChannel" {
+			conns <- conn
+		}
+	})
+	defer shutdown()
+
+	first, second := make(chan Ticker, 1), make(chan Ticker, 1)
+	s1, err := c.SubscribeTicker("btc_usdt", func(ticker Ticker) {
+		first <- ticker
+	})
+	assert.Nil(t, err)
+	s2, err := c.SubscribeTicker("btc_usdt", func(ticker Ticker) {
+		second <- ticker
+	})
+	assert.Nil(t, err)
+
+	// push only once both listeners are registered
+	select {
+	case conn := <-conns:
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"channel":"btcusdt_ticker","date":"1517454000000","ticker":{"last":"10"}}`))
+	case <-time.After(time.Second):
+		t.Fatal("addChannel was not sent")
+	}
+
+	for _, tickers := range []chan Ticker{first, second} {
+		select {
+		case ticker := <-tickers:
+			assert.Equal(t, 10.0, ticker.Last)
+		case <-time.After(time.Second):
+			t.Fatal("ticker was not fanned out to every listener")
+		}
+	}
+
+	assert.Nil(t, s1.Unsubscribe())
+	assert.Nil(t, s1.Unsubscribe())
+	assert.Nil(t, s2.Unsubscribe())
+
+	for _, expected := range []string{"addChannel", "removeChannel"} {
+		select {
+		case event := <-events:
+			assert.Equal(t, expected, event)
+		case <-time.After(time.Second):
+			t.Fatal("missing " + expected)
+		}
+	}
+	select {
+	case event := <-events:
+		t.Fatal("unexpected " + event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestZbWebSocketClient_SubscribeUnsubscribeConcurrently(t *testing.T) {
+	var mutex sync.Mutex
+	var events []string
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		mutex.Lock()
+		events = append(events, message["event"].(string))
+		mutex.Unlock()
+	})
+	defer shutdown()
+
+	// widen the window between dropping the last listener and sending removeChannel
+	c.Hooks = []WsHook{func(frame *Frame) {
+		if frame.Outgoing && strings.Contains(string(frame.Payload), "removeChannel") {
+			time.Sleep(time.Millisecond)
+		}
+	}}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				s, err := c.SubscribeTicker("btc_usdt", func(ticker Ticker) {})
+				assert.Nil(t, err)
+				assert.Nil(t, s.Unsubscribe())
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the server must always see the channel added before it is removed and the other way around
+	assert.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(events) > 0 && events[len(events)-1] == "removeChannel"
+	}, time.Second, 10*time.Millisecond)
+	mutex.Lock()
+	defer mutex.Unlock()
+	for i, event := range events {
+		if i%2 == 0 {
+			assert.Equal(t, "addChannel", event)
+		} else {
+			assert.Equal(t, "removeChannel", event)
+		}
+	}
+}
+
+func TestZbWebSocketClient_StreamIsolation(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		switch message["channel"] {
+		case "btcusdt_depth":
+			for i := 0; i < 20; i++ {
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"asks":[],"bids":[],"channel":"btcusdt_depth","timestamp":`+strconv.Itoa(i)+`}`))
+			}
+		case "btcusdt_ticker":
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"channel":"btcusdt_ticker","date":"1517454000000","ticker":{"last":"10"}}`))
+		}
+	})
+	defer shutdown()
+
+	// nobody reads the depths, the oldest are dropped instead of stalling the tickers
+	depths, depthSubscription, err := c.StreamDepth("btc_usdt", StreamOptions{Buffer: 2, Policy: DropOldest})
+	assert.Nil(t, err)
+	tickers, tickerSubscription, err := c.StreamTicker("btc_usdt", StreamOptions{Buffer: 1, Policy: KeepLatest})
+	assert.Nil(t, err)
+
+	select {
+	case ticker := <-tickers:
+		assert.Equal(t, 10.0, ticker.Last)
+	case <-time.After(time.Second):
+		t.Fatal("ticker stream was stalled by the depth stream")
+	}
+
+	// at most one depth held by the dispatcher and the two latest queued ones are left
+	var times []uint64
+	for len(times) == 0 || times[len(times)-1] != 19 {
+		select {
+		case depth := <-depths:
+			times = append(times, depth.Time)
+		case <-time.After(time.Second):
+			t.Fatal("no depth received", times)
+		}
+	}
+	assert.True(t, len(times) <= 3)
+	assert.Equal(t, uint64(18), times[len(times)-2])
+
+	assert.Nil(t, tickerSubscription.Unsubscribe())
+	assert.Nil(t, depthSubscription.Unsubscribe())
+	_, ok := <-tickers
+	assert.False(t, ok)
+	_, ok = <-depths
+	assert.False(t, ok)
+}
+
+func TestZbWebSocketClient_StreamDisconnect(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {})
+	defer shutdown()
+
+	tickers, s, err := c.StreamTicker("btc_usdt", StreamOptions{Buffer: 1, Policy: KeepLatest})
+	assert.Nil(t, err)
+	c.Disconnect()
+
+	select {
+	case _, ok := <-tickers:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("stream was not closed by Disconnect")
+	}
+	assert.Nil(t, s.Unsubscribe())
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	assert.Empty(t, c.streams)
+	assert.Empty(t, c.decoders)
+}
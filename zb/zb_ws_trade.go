@@ -0,0 +1,202 @@
+package zb
+
+import (
+	encoding "encoding/json"
+	"errors"
+	. "github.com/berryland/x"
+	json "github.com/buger/jsonparser"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type pendingRequest struct {
+	channel  string
+	response chan []byte
+}
+
+func (c *ZbWebSocketClient) PlaceOrder(symbol string, price, amount float64, tradeType TradeType, accessKey, secretKey string) (uint64, error) {
+	params := map[string]string{
+		"price":     strconv.FormatFloat(price, 'f', -1, 64),
+		"amount":    strconv.FormatFloat(amount, 'f', -1, 64),
+		"tradeType": strconv.Itoa(int(tradeType)),
+	}
+	bytes, err := c.request(getPrivateChannel(symbol, "order"), params, accessKey, secretKey)
+	if err != nil {
+		return 0, err
+	}
+
+	if id, err := json.GetInt(bytes, "data", "entrustId"); err == nil {
+		return uint64(id), nil
+	}
+	idString, _ := json.GetString(bytes, "data", "entrustId")
+	id, _ := strconv.ParseUint(idString, 10, 64)
+	return id, nil
+}
+
+func (c *ZbWebSocketClient) CancelOrder(symbol string, id uint64, accessKey, secretKey string) error {
+	_, err := c.request(getPrivateChannel(symbol, "cancelorder"), map[string]string{"id": strconv.FormatUint(id, 10)}, accessKey, secretKey)
+	return err
+}
+
+func (c *ZbWebSocketClient) GetOrder(symbol string, id uint64, accessKey, secretKey string) (Order, error) {
+	bytes, err := c.request(getPrivateChannel(symbol, "getorder"), map[string]string{"id": strconv.FormatUint(id, 10)}, accessKey, secretKey)
+	if err != nil {
+		return Order{}, err
+	}
+
+	data, _, _, _ := json.Get(bytes, "data")
+	return parseOrder(data), nil
+}
+
+// GetOrders returns a page of the orders of symbol, pages start at 1. ZB fixes the page size to 10 when tradeType is
+// Buy or Sell, so size only applies to All.
+func (c *ZbWebSocketClient) GetOrders(symbol string, tradeType TradeType, page uint64, size uint16, accessKey, secretKey string) ([]Order, error) {
+	params := map[string]string{
+		"pageIndex": strconv.FormatUint(page, 10),
+	}
+	channel := getPrivateChannel(symbol, "getordersignoretradetype")
+	switch tradeType {
+	case All:
+		params["pageSize"] = strconv.Itoa(int(size))
+	case Buy, Sell:
+		params["tradeType"] = strconv.Itoa(int(tradeType))
+		channel = getPrivateChannel(symbol, "getorders")
+	default:
+		return []Order{}, errors.New("Unsupported trade type: " + strconv.Itoa(int(tradeType)))
+	}
+
+	bytes, err := c.request(channel, params, accessKey, secretKey)
+	if err != nil {
+		return []Order{}, err
+	}
+
+	var orders []Order
+	json.ArrayEach(bytes, func(value []byte, dataType json.ValueType, offset int, err error) {
+		orders = append(orders, parseOrder(value))
+	}, "data")
+
+	return orders, nil
+}
+
+func (c *ZbWebSocketClient) GetAccount(accessKey, secretKey string) (Account, error) {
+	bytes, err := c.request("getaccountinfo", map[string]string{}, accessKey, secretKey)
+	if err != nil {
+		return Account{}, err
+	}
+
+	data, _, _, _ := json.Get(bytes, "data")
+	return marshalAccount(data), nil
+}
+
+// request sends a signed event on channel and waits for the response carrying the same no. When the server leaves no
+// out, responses on channel are matched to the requests in the order they were sent.
+func (c *ZbWebSocketClient) request(channel string, params map[string]string, accessKey, secretKey string) ([]byte, error) {
+	no := c.nextNo()
+	params["event"] = "addChannel"
+	params["channel"] = channel
+	params["accesskey"] = accessKey
+	params["no"] = no
+	sign, err := genMessageSign(secretKey, params)
+	if err != nil {
+		return nil, err
+	}
+	params["sign"] = sign
+
+	// queue and send under one lock so that the queue of channel is in the order the requests hit the wire
+	response := make(chan []byte, 1)
+	c.requesting.Lock()
+	c.mutex.Lock()
+	c.pending[no] = pendingRequest{channel: channel, response: response}
+	c.queued[channel] = append(c.queued[channel], no)
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		c.forget(channel, no)
+		c.mutex.Unlock()
+	}()
+
+	err = c.send(channel, params)
+	c.requesting.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case bytes, ok := <-response:
+		if !ok {
+			return nil, ErrNotConnected
+		}
+		if err := extractTradeApiError(bytes); err != nil {
+			return nil, err
+		}
+		return bytes, nil
+	case <-time.After(c.Timeout):
+		return nil, ErrTimeout
+	}
+}
+
+// respond hands bytes to the request waiting for it and reports whether there was one.
+func (c *ZbWebSocketClient) respond(channel string, bytes []byte) bool {
+	no, _ := json.GetString(bytes, "no")
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if no == "" && len(c.queued[channel]) > 0 {
+		no = c.queued[channel][0]
+	}
+
+	p, ok := c.pending[no]
+	if !ok {
+		return false
+	}
+	c.forget(p.channel, no)
+	p.response <- bytes
+	return true
+}
+
+// forget removes the request no from pending and from the queue of channel, the caller must hold the mutex.
+func (c *ZbWebSocketClient) forget(channel string, no string) {
+	delete(c.pending, no)
+	queue := c.queued[channel]
+	for i, n := range queue {
+		if n != no {
+			continue
+		}
+		if len(queue) == 1 {
+			delete(c.queued, channel)
+		} else {
+			c.queued[channel] = append(append([]string(nil), queue[:i]...), queue[i+1:]...)
+		}
+		return
+	}
+}
+
+// failPending makes every waiting request fail with ErrNotConnected, the caller must hold the mutex.
+func (c *ZbWebSocketClient) failPending() {
+	for no, p := range c.pending {
+		delete(c.pending, no)
+		close(p.response)
+	}
+	c.queued = make(map[string][]string)
+}
+
+func (c *ZbWebSocketClient) nextNo() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.no++
+	return strconv.FormatInt(time.Now().UnixNano(), 10) + strconv.FormatUint(c.no, 10)
+}
+
+func getPrivateChannel(symbol string, name string) string {
+	return strings.Replace(symbol, "_", "", 1) + "_" + name
+}
+
+// genMessageSign signs the compact json of params with its keys sorted, which is what encoding/json produces for maps.
+func genMessageSign(secretKey string, params map[string]string) (string, error) {
+	bytes, err := encoding.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	return genSignBytes(secretKey, bytes), nil
+}
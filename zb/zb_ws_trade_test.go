@@ -0,0 +1,187 @@
+package zb
+
+import (
+	. "github.com/berryland/x"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGenMessageSign(t *testing.T) {
+	sign, err := genMessageSign("secret", map[string]string{"event": "addChannel", "channel": "getaccountinfo", "accesskey": "access", "no": "1"})
+	assert.Nil(t, err)
+	assert.Equal(t, genSignBytes("secret", []byte(`{"accesskey":"access","channel":"getaccountinfo","event":"addChannel","no":"1"}`)), sign)
+}
+
+func TestZbWebSocketClient_PlaceOrder(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		assert.Equal(t, "btcusdt_order", message["channel"])
+		assert.Equal(t, "addChannel", message["event"])
+		assert.Equal(t, "access", message["accesskey"])
+		assert.Equal(t, "6500.5", message["price"])
+		assert.Equal(t, "0.01", message["amount"])
+		assert.Equal(t, "1", message["tradeType"])
+
+		params := map[string]string{}
+		for k, v := range message {
+			if k != "sign" {
+				params[k] = v.(string)
+			}
+		}
+		sign, _ := genMessageSign("secret", params)
+		assert.Equal(t, sign, message["sign"])
+
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"success":true,"code":1000,"data":{"entrustId":201711133673},"channel":"btcusdt_order","message":"success","no":"`+message["no"].(string)+`"}`))
+	})
+	defer shutdown()
+
+	id, err := c.PlaceOrder("btc_usdt", 6500.5, 0.01, Buy, "access", "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(201711133673), id)
+}
+
+func TestZbWebSocketClient_CancelOrder(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		assert.Equal(t, "201711133673", message["id"])
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"success":false,"code":3001,"channel":"btcusdt_cancelorder","message":"order not found","no":"`+message["no"].(string)+`"}`))
+	})
+	defer shutdown()
+
+	err := c.CancelOrder("btc_usdt", 201711133673, "access", "secret")
+	assert.Equal(t, OrderNotFound, err.(*ApiError).Code)
+}
+
+func TestZbWebSocketClient_GetOrders(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		assert.Equal(t, "btcusdt_getordersignoretradetype", message["channel"])
+		assert.Equal(t, "2", message["pageIndex"])
+		assert.Equal(t, "20", message["pageSize"])
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"success":true,"code":1000,"channel":"btcusdt_getordersignoretradetype","no":"`+message["no"].(string)+`","data":[{"currency":"btc_usdt","id":"20160902387645980","price":6500,"status":3,"total_amount":0.01,"trade_amount":0.005,"trade_price":6500,"trade_date":1472814905567,"trade_money":32.5,"type":1}]}`))
+	})
+	defer shutdown()
+
+	orders, err := c.GetOrders("btc_usdt", All, 2, 20, "access", "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, []Order{{Id: 20160902387645980, Price: 6500, Average: 6500, TotalAmount: 0.01, TradeAmount: 0.005, TradeMoney: 32.5, Symbol: "btc_usdt", Status: PartiallyFilled, TradeType: Buy, Time: 1472814905567}}, orders)
+}
+
+func TestZbWebSocketClient_GetAccount(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		assert.Equal(t, "getaccountinfo", message["channel"])
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"success":true,"code":1000,"channel":"getaccountinfo","data":{"coins":[{"freez":"1.5","enName":"BTC","unitDecimal":8,"cnName":"BTC","unitTag":"฿","available":"0.5","key":"btc"}],"base":{"username":"user","trade_password_enabled":true,"auth_google_enabled":false,"auth_mobile_enabled":true}}}`))
+	})
+	defer shutdown()
+
+	account, err := c.GetAccount("access", "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, Account{
+		Username:             "user",
+		TradePasswordEnabled: true,
+		AuthMobileEnabled:    true,
+		Assets:               []Asset{{Freeze: 1.5, Available: 0.5, Coin: Coin{CnName: "BTC", EnName: "BTC", Key: "btc", Unit: "฿", Scale: 8}}},
+	}, account)
+}
+
+func TestZbWebSocketClient_RequestTimeout(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {})
+	defer shutdown()
+
+	c.Timeout = 50 * time.Millisecond
+	_, err := c.GetOrder("btc_usdt", 1, "access", "secret")
+	assert.Equal(t, ErrTimeout, err)
+}
+
+func TestZbWebSocketClient_RequestDropped(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		conn.Close()
+	})
+	defer shutdown()
+
+	start := time.Now()
+	_, err := c.GetOrder("btc_usdt", 1, "access", "secret")
+	assert.Equal(t, ErrNotConnected, err)
+	assert.True(t, time.Since(start) < c.Timeout)
+}
+
+func TestZbWebSocketClient_ResponsesWithoutNo(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		time.Sleep(10 * time.Millisecond)
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"success":true,"code":1000,"channel":"getaccountinfo","data":{"coins":[],"base":{"username":"`+message["accesskey"].(string)+`"}}}`))
+	})
+	defer shutdown()
+
+	var wg sync.WaitGroup
+	for _, accessKey := range []string{"a", "b", "c", "d"} {
+		wg.Add(1)
+		go func(accessKey string) {
+			defer wg.Done()
+			account, err := c.GetAccount(accessKey, "secret")
+			assert.Nil(t, err)
+			assert.Equal(t, accessKey, account.Username)
+		}(accessKey)
+	}
+	wg.Wait()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	assert.Empty(t, c.queued)
+}
+
+func TestZbWebSocketClient_HooksRedactSecrets(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"success":true,"code":1000,"channel":"btcusdt_cancelorder","no":"`+message["no"].(string)+`"}`))
+	})
+	defer shutdown()
+
+	var frames []*Frame
+	c.Hooks = []WsHook{func(frame *Frame) {
+		if frame.Outgoing {
+			frames = append(frames, frame)
+		}
+	}}
+	assert.Nil(t, c.CancelOrder("btc_usdt", 1, "access", "secret"))
+
+	assert.Equal(t, 1, len(frames))
+	payload := string(frames[0].Payload)
+	assert.Contains(t, payload, `"accesskey":"***"`)
+	assert.Contains(t, payload, `"sign":"***"`)
+	assert.NotContains(t, payload, "access\"")
+}
+
+func TestZbWebSocketClient_GetOrdersUnsupportedTradeType(t *testing.T) {
+	c := NewWebSocketClient()
+	_, err := c.GetOrders("btc_usdt", TradeType(7), 1, 10, "access", "secret")
+	assert.EqualError(t, err, "Unsupported trade type: 7")
+}
+
+func TestZbWebSocketClient_PlaceOrderFromCallback(t *testing.T) {
+	c, shutdown := newTestWebSocketClient(t, func(conn *websocket.Conn, message map[string]interface{}) {
+		switch message["channel"] {
+		case "btcusdt_ticker":
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"channel":"btcusdt_ticker","date":"1517454000000","ticker":{"last":"10"}}`))
+		case "btcusdt_order":
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"success":true,"code":1000,"channel":"btcusdt_order","no":"`+message["no"].(string)+`","data":{"entrustId":201711133673}}`))
+		}
+	})
+	defer shutdown()
+
+	type result struct {
+		id  uint64
+		err error
+	}
+	results := make(chan result, 1)
+	_, err := c.SubscribeTicker("btc_usdt", func(ticker Ticker) {
+		id, err := c.PlaceOrder("btc_usdt", ticker.Last, 1, Buy, "access", "secret")
+		results <- result{id, err}
+	})
+	assert.Nil(t, err)
+
+	select {
+	case r := <-results:
+		assert.Nil(t, r.err)
+		assert.Equal(t, uint64(201711133673), r.id)
+	case <-time.After(time.Second):
+		t.Fatal("the order placed from a callback got no response")
+	}
+}